@@ -4,7 +4,8 @@ go 1.25.5
 
 require (
 	github.com/sirupsen/logrus v1.9.4
+	golang.org/x/term v0.36.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require golang.org/x/sys v0.13.0 // indirect
+require golang.org/x/sys v0.37.0 // indirect
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"faynoSync-cli/internal/config"
+)
+
+const tokenExpiryWarning = 10 * time.Minute
+
+type apiClient struct {
+	ctx         context.Context
+	profile     string
+	server      string
+	token       string
+	tokenSource string
+	owner       string
+	oidc        config.OIDC
+	headers     map[string]string
+	retries     int
+	limiter     *rateLimiter
+	// contentLength sends uploads with Content-Length, not chunked.
+	contentLength bool
+	http          *http.Client
+
+	// refresh obtains a new token after a 401; it is used at most once.
+	refresh func() (string, error)
+}
+
+type requestBody func() (io.Reader, string)
+
+// sizedReader is a streamed body whose length is known up front, so it is
+// sent with Content-Length rather than chunked encoding.
+type sizedReader struct {
+	io.Reader
+	size int64
+}
+
+type apiError struct {
+	Status int
+	Body   string
+}
+
+func (e *apiError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("server returned status %d", e.Status)
+	}
+	return fmt.Sprintf("server returned status %d: %s", e.Status, e.Body)
+}
+
+func (a *App) newAPIClient() (*apiClient, error) {
+	runtimeCfg, _, err := config.LoadRuntime(a.configPath, a.profile)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeCfg.HTTP = a.httpSettings(runtimeCfg.HTTP)
+	a.logProxy(runtimeCfg.Server, runtimeCfg.HTTP)
+	client, err := newClientFor(a.ctx, runtimeCfg)
+	if err != nil {
+		return nil, err
+	}
+	client.refresh = a.tokenRefresher(client, runtimeCfg)
+	if client.limiter, err = a.uploadLimiter(runtimeCfg.HTTP.LimitRate); err != nil {
+		return nil, err
+	}
+
+	if err := a.checkTokenExpiry(client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (a *App) newPublicClient() (*apiClient, error) {
+	runtimeCfg, _, err := config.LoadServer(a.configPath, a.profile)
+	if err != nil {
+		return nil, err
+	}
+
+	runtimeCfg.HTTP = a.httpSettings(runtimeCfg.HTTP)
+	a.logProxy(runtimeCfg.Server, runtimeCfg.HTTP)
+	return newClientFor(a.ctx, runtimeCfg)
+}
+
+func newClientFor(ctx context.Context, runtimeCfg config.RuntimeConfig) (*apiClient, error) {
+	httpClient, err := newHTTPClient(runtimeCfg.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiClient{
+		ctx:         ctx,
+		profile:     runtimeCfg.Profile,
+		server:      strings.TrimRight(runtimeCfg.Server, "/"),
+		token:       runtimeCfg.Token,
+		tokenSource: runtimeCfg.TokenSource,
+		owner:       runtimeCfg.Owner,
+		oidc:        runtimeCfg.OIDC,
+		headers:     runtimeCfg.HTTP.Headers,
+		retries:     runtimeCfg.HTTP.Retries,
+		http:        httpClient,
+	}, nil
+}
+
+// tokenRefresher renews a token through the source it came from. Tokens
+// passed in through FAYNOSYNC_TOKEN or FAYNOSYNC_TOKEN_FILE are never
+// replaced, so an expired one is reported instead.
+func (a *App) tokenRefresher(client *apiClient, runtimeCfg config.RuntimeConfig) func() (string, error) {
+	switch runtimeCfg.TokenSource {
+	case config.TokenSourceHelper:
+		return func() (string, error) {
+			return config.RefreshHelperToken(runtimeCfg)
+		}
+	case config.TokenSourceCredentials:
+		if !oidcConfigured(runtimeCfg.OIDC) {
+			return a.loginRefresher(client)
+		}
+		return func() (string, error) {
+			token, err := client.exchangeIDToken(runtimeCfg.OIDC)
+			if err != nil {
+				return "", err
+			}
+			if _, err := config.StoreToken(runtimeCfg.Profile, runtimeCfg.Server, token); err != nil {
+				a.logger.WithError(err).Warn("Could not store refreshed token")
+			}
+			return token, nil
+		}
+	default:
+		return nil
+	}
+}
+
+func (a *App) checkTokenExpiry(client *apiClient) error {
+	source := client.tokenSource
+	expiresAt, ok := tokenExpiry(client.token)
+	if !ok {
+		return nil
+	}
+	a.logger.WithFields(map[string]any{
+		"source":     source,
+		"expires_at": expiresAt.Format(time.RFC3339),
+	}).Debug("Token loaded")
+
+	remaining := time.Until(expiresAt)
+	switch {
+	case remaining <= 0 && client.refresh == nil:
+		return fmt.Errorf("token from %s expired at %s, run faynosync login or provide a new token", source, expiresAt.Format(time.RFC3339))
+	case remaining <= 0:
+		if err := client.refreshToken(); err != nil {
+			return fmt.Errorf("token from %s expired at %s and could not be refreshed: %w", source, expiresAt.Format(time.RFC3339), err)
+		}
+		a.logger.WithField("source", source).Info("Token expired, obtained a new one")
+	case remaining < tokenExpiryWarning:
+		a.logger.WithFields(map[string]any{
+			"source":     source,
+			"expires_at": expiresAt.Format(time.RFC3339),
+		}).Warn("Token expires soon")
+	}
+	return nil
+}
+
+func (c *apiClient) refreshToken() error {
+	refresh := c.refresh
+	c.refresh = nil
+
+	token, err := refresh()
+	if err != nil {
+		return err
+	}
+	c.token = token
+	return nil
+}
+
+func oidcConfigured(settings config.OIDC) bool {
+	return strings.TrimSpace(settings.TokenFile) != "" ||
+		strings.TrimSpace(settings.TokenEnv) != "" ||
+		strings.TrimSpace(os.Getenv(config.EnvIDToken)) != ""
+}
+
+func (c *apiClient) endpoint(path string, query url.Values) string {
+	out := c.server + path
+	if len(query) > 0 {
+		out += "?" + query.Encode()
+	}
+	return out
+}
+
+func (c *apiClient) do(method, path string, query url.Values, body requestBody) ([]byte, error) {
+	respBody, err := c.send(method, path, query, body)
+	for attempt := 0; err != nil && attempt < c.retries && retryable(method, err); attempt++ {
+		select {
+		case <-time.After(retryBackoff << attempt):
+		case <-c.ctx.Done():
+			return nil, context.Cause(c.ctx)
+		}
+		respBody, err = c.send(method, path, query, body)
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized && c.refresh != nil {
+		if refreshErr := c.refreshToken(); refreshErr != nil {
+			return nil, fmt.Errorf("%w (token refresh failed: %v)", err, refreshErr)
+		}
+		return c.send(method, path, query, body)
+	}
+
+	return respBody, err
+}
+
+func (c *apiClient) send(method, path string, query url.Values, body requestBody) ([]byte, error) {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		reader, contentType = body()
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.endpoint(path, query), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if sized, ok := reader.(*sizedReader); ok {
+		req.ContentLength = sized.size
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, &apiError{
+			Status: resp.StatusCode,
+			Body:   strings.TrimSpace(string(respBody)),
+		}
+	}
+
+	return respBody, nil
+}
+
+func (c *apiClient) getJSON(path string, query url.Values, out any) error {
+	respBody, err := c.do(http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *apiClient) sendJSON(method, path string, query url.Values, in, out any) error {
+	var body requestBody
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = func() (io.Reader, string) {
+			return bytes.NewReader(raw), "application/json"
+		}
+	}
+
+	respBody, err := c.do(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(bytes.TrimSpace(respBody)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -2,32 +2,100 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 
 	"faynoSync-cli/internal/config"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
 )
 
 type App struct {
-	in     io.Reader
-	out    io.Writer
-	br     *bufio.Reader
-	logger *logrus.Logger
+	// ctx is the context passed to Run; it is cancelled on SIGINT/SIGTERM.
+	ctx        context.Context
+	in         io.Reader
+	out        io.Writer
+	br         *bufio.Reader
+	logger     *logrus.Logger
+	profile    string
+	configPath string
+	// httpOverrides holds the global TLS, proxy, rate and timeout flags.
+	httpOverrides config.HTTP
+	limiter       *rateLimiter
 }
 
-func (a *App) Run(args []string) error {
-	levelName, remaining, err := parseGlobalFlags(args)
+// Run executes the command in args. When ctx is cancelled, in-flight requests
+// and prompts stop and the returned error carries the cancellation cause.
+func (a *App) Run(ctx context.Context, args []string) error {
+	a.ctx = ctx
+	err := a.run(args)
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	cause := context.Cause(ctx)
+	if errors.Is(err, cause) {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return cause
+	}
+	return fmt.Errorf("%w: %v", cause, err)
+}
+
+func (a *App) run(args []string) error {
+	global, remaining, err := parseGlobalFlags(args)
 	if err != nil {
 		return err
 	}
-	if err := a.setLogLevel(levelName); err != nil {
+	if err := a.setLogLevel(global.LogLevel); err != nil {
 		return err
 	}
+	a.profile = strings.TrimSpace(global.Profile)
+	a.httpOverrides = config.HTTP{
+		CABundle:   strings.TrimSpace(global.CABundle),
+		ClientCert: strings.TrimSpace(global.ClientCert),
+		ClientKey:  strings.TrimSpace(global.ClientKey),
+		MinTLS:     strings.TrimSpace(global.MinTLS),
+		Insecure:   global.Insecure,
+		Proxy:      strings.TrimSpace(global.Proxy),
+		NoProxy:    strings.TrimSpace(global.NoProxy),
+		LimitRate:  strings.TrimSpace(global.LimitRate),
+
+		Timeout:         strings.TrimSpace(global.Timeout),
+		ConnectTimeout:  strings.TrimSpace(global.ConnectTimeout),
+		TLSTimeout:      strings.TrimSpace(global.TLSTimeout),
+		ResponseTimeout: strings.TrimSpace(global.ResponseTimeout),
+		StallTimeout:    strings.TrimSpace(global.StallTimeout),
+	}
+	if a.httpOverrides.LimitRate != "" {
+		if _, err := config.ParseRate(a.httpOverrides.LimitRate); err != nil {
+			return fmt.Errorf("--limit-rate: %w", err)
+		}
+	}
+	for flag, value := range map[string]string{
+		"--timeout":          a.httpOverrides.Timeout,
+		"--connect-timeout":  a.httpOverrides.ConnectTimeout,
+		"--tls-timeout":      a.httpOverrides.TLSTimeout,
+		"--response-timeout": a.httpOverrides.ResponseTimeout,
+		"--stall-timeout":    a.httpOverrides.StallTimeout,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := config.ParseTimeout(value); err != nil {
+			return fmt.Errorf("%s: %w", flag, err)
+		}
+	}
+	a.configPath = strings.TrimSpace(global.Config)
+	a.warnReadableConfig()
 
 	args = remaining
 
@@ -38,11 +106,31 @@ func (a *App) Run(args []string) error {
 
 	switch args[0] {
 	case "init":
-		return a.initConfig()
+		return a.runInit(args[1:])
 	case "config":
 		return a.runConfig(args[1:])
+	case "login":
+		return a.runLogin(args[1:])
+	case "logout":
+		return a.runLogout(args[1:])
+	case "auth":
+		return a.runAuth(args[1:])
+	case "whoami":
+		return a.runWhoami(args[1:])
+	case "env":
+		return a.runEnv(args[1:])
 	case "upload":
 		return a.runUpload(args[1:])
+	case "delete":
+		return a.runDelete(args[1:])
+	case "check":
+		return a.runCheck(args[1:])
+	case "promote":
+		return a.runPromote(args[1:])
+	case "rollback":
+		return a.runRollback(args[1:])
+	case "prune":
+		return a.runPrune(args[1:])
 	case "-h", "--help", "help":
 		a.printRootUsage()
 		return nil
@@ -51,6 +139,22 @@ func (a *App) Run(args []string) error {
 	}
 }
 
+// warnReadableConfig flags config and credential files that other users on
+// the machine can read. Both are rewritten with mode 0600 on the next save.
+func (a *App) warnReadableConfig() {
+	paths := []func() (string, error){
+		func() (string, error) { return config.Path(a.configPath) },
+		config.CredentialsPath,
+	}
+	for _, pathFn := range paths {
+		path, err := pathFn()
+		if err != nil || !config.WorldReadable(path) {
+			continue
+		}
+		a.logger.WithField("path", path).Warn("File is readable by other users, run: chmod 600 " + path)
+	}
+}
+
 func (a *App) runConfig(args []string) error {
 	if len(args) == 0 {
 		a.printConfigUsage()
@@ -59,9 +163,23 @@ func (a *App) runConfig(args []string) error {
 
 	switch args[0] {
 	case "view":
-		return a.viewConfig()
+		return a.viewConfig(args[1:])
 	case "set":
 		return a.setConfig(args[1:])
+	case "get":
+		return a.getConfig(args[1:])
+	case "unset":
+		return a.unsetConfig(args[1:])
+	case "validate":
+		return a.validateConfig(args[1:])
+	case "migrate":
+		return a.migrateConfig(args[1:])
+	case "use":
+		return a.useProfile(args[1:])
+	case "list":
+		return a.listProfiles()
+	case "delete-profile":
+		return a.deleteProfile(args[1:])
 	case "-h", "--help", "help":
 		a.printConfigUsage()
 		return nil
@@ -70,57 +188,51 @@ func (a *App) runConfig(args []string) error {
 	}
 }
 
-func (a *App) initConfig() error {
-
-	path, err := config.Path()
-	if err != nil {
-		return err
+func (a *App) viewConfig(args []string) error {
+	showOrigin := false
+	for _, arg := range args {
+		switch strings.TrimSpace(arg) {
+		case "--show-origin":
+			showOrigin = true
+		default:
+			return fmt.Errorf("unknown config view flag: %s", arg)
+		}
 	}
-	if _, err := os.Stat(path); err == nil {
-		a.logger.WithField("path", path).Info("Config already exists")
-		return nil
+	if showOrigin {
+		return a.viewOrigins()
 	}
 
-	server, err := a.promptValueWithDefault("server", config.DefaultServer)
-	if err != nil {
-		return err
-	}
-	a.logger.WithField("server", server).Debug("Server value")
-	owner, err := a.promptValueWithDefault("owner", config.DefaultOwner)
+	cfg, _, err := config.Load(a.configPath)
 	if err != nil {
 		return err
 	}
-	a.logger.WithField("owner", owner).Debug("Owner value")
-	path, err = config.Init(config.Config{
-		Server: server,
-		Owner:  owner,
-	})
+
+	out, err := config.Marshal(cfg)
 	if err != nil {
 		return err
 	}
 
-	a.logger.WithField("path", path).Info("Config initialized")
+	_, _ = fmt.Fprint(a.out, string(out))
 	return nil
 }
 
-func (a *App) viewConfig() error {
-	cfg, _, err := config.Load()
+func (a *App) viewOrigins() error {
+	settings, err := config.Resolve(a.configPath, a.profile)
 	if err != nil {
 		return err
 	}
 
-	out, err := config.Marshal(cfg)
-	if err != nil {
-		return err
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "profile\t%s\t\n", settings.Profile)
+	for _, entry := range settings.Entries() {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Key, entry.Value, entry.Origin)
 	}
-
-	_, _ = fmt.Fprint(a.out, string(out))
-	return nil
+	return w.Flush()
 }
 
 func (a *App) setConfig(args []string) error {
 	if len(args) < 1 {
-		return errors.New("usage: faynosync config set <server|owner> [value]")
+		return errors.New("usage: faynosync config set <key> [value]")
 	}
 
 	key := args[0]
@@ -139,27 +251,276 @@ func (a *App) setConfig(args []string) error {
 		return errors.New("value cannot be empty")
 	}
 
-	cfg, path, err := config.Load()
+	name := ""
+	_, err := config.Update(a.configPath, func(cfg *config.Config) error {
+		name = config.ActiveProfile(*cfg, a.profile)
+		profile := cfg.Profiles[name]
+		if err := config.SetKey(&profile, key, value); err != nil {
+			return err
+		}
+		cfg.SetProfile(name, profile)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := config.UpdateField(&cfg, key, value); err != nil {
+	a.logger.WithFields(map[string]any{
+		"key":     key,
+		"profile": name,
+	}).Info("Config updated")
+	return nil
+}
+
+func (a *App) getConfig(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: faynosync config get <key>")
+	}
+
+	cfg, _, err := config.Load(a.configPath)
+	if err != nil {
 		return err
 	}
 
-	if err := config.SaveAt(path, cfg); err != nil {
+	value, err := config.GetKey(cfg.Profiles[config.ActiveProfile(cfg, a.profile)], args[0])
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(a.out, value)
+	return nil
+}
+
+func (a *App) unsetConfig(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: faynosync config unset <key>")
+	}
+
+	name := ""
+	_, err := config.Update(a.configPath, func(cfg *config.Config) error {
+		name = config.ActiveProfile(*cfg, a.profile)
+		profile, err := cfg.Profile(name)
+		if err != nil {
+			return err
+		}
+		if err := config.UnsetKey(&profile, args[0]); err != nil {
+			return err
+		}
+		cfg.SetProfile(name, profile)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
-	a.logger.WithField("key", key).Info("Config updated")
+	a.logger.WithFields(map[string]any{
+		"key":     args[0],
+		"profile": name,
+	}).Info("Config key removed")
 	return nil
 }
 
+// validateConfig checks the given files, or the user config and the nearest
+// project config when no path is given.
+func (a *App) validateConfig(args []string) error {
+	type target struct {
+		path    string
+		project bool
+	}
+
+	var targets []target
+	for _, arg := range args {
+		targets = append(targets, target{path: arg, project: filepath.Base(arg) == config.ProjectFileName})
+	}
+	if len(targets) == 0 {
+		path, err := config.Path(a.configPath)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil {
+			targets = append(targets, target{path: path})
+		}
+
+		project, err := config.FindProject()
+		if err != nil {
+			return err
+		}
+		if project != "" {
+			targets = append(targets, target{path: project, project: true})
+		}
+	}
+	if len(targets) == 0 {
+		return errors.New("no config file found, run: faynosync init")
+	}
+
+	total := 0
+	for _, t := range targets {
+		validate := config.ValidateFile
+		if t.project {
+			validate = config.ValidateProjectFile
+		}
+
+		problems, err := validate(t.path)
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			_, _ = fmt.Fprintf(a.out, "%s: %s\n", t.path, problem)
+		}
+		total += len(problems)
+
+		if len(problems) == 0 {
+			a.logger.WithField("path", t.path).Info("Config is valid")
+		}
+	}
+
+	if total > 0 {
+		return fmt.Errorf("config validation failed: %d problem(s)", total)
+	}
+	return nil
+}
+
+func (a *App) migrateConfig(args []string) error {
+	dryRun := false
+	for _, arg := range args {
+		switch strings.TrimSpace(arg) {
+		case "--dry-run":
+			dryRun = true
+		default:
+			return fmt.Errorf("unknown config migrate flag: %s", arg)
+		}
+	}
+
+	path, err := config.Path(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		applied, backup, err := config.MigrateFile(path)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			a.logger.WithField("version", config.CurrentVersion).Info("Config is up to date")
+			return nil
+		}
+		for _, m := range applied {
+			a.logger.WithField("to", m.To).Info("Migrated config: " + m.Description)
+		}
+		a.logger.WithFields(map[string]any{
+			"path":   path,
+			"backup": backup,
+		}).Info("Config migrated")
+		return nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	migrated, applied, err := config.Migrate(raw)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		a.logger.WithField("version", config.CurrentVersion).Info("Config is up to date")
+		return nil
+	}
+
+	for _, m := range applied {
+		_, _ = fmt.Fprintf(a.out, "version %d -> %d: %s\n", m.From, m.To, m.Description)
+	}
+	writeDiff(a.out, path, fmt.Sprintf("%s (version %d)", path, config.CurrentVersion), lineDiff(string(raw), string(migrated)))
+	return nil
+}
+
+func (a *App) useProfile(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: faynosync config use <profile>")
+	}
+
+	_, err := config.Update(a.configPath, func(cfg *config.Config) error {
+		return cfg.Use(args[0])
+	})
+	if err != nil {
+		return err
+	}
+
+	a.logger.WithField("profile", args[0]).Info("Switched profile")
+	return nil
+}
+
+func (a *App) listProfiles() error {
+	cfg, _, err := config.Load(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	active := config.ActiveProfile(cfg, a.profile)
+	for _, name := range cfg.ProfileNames() {
+		marker := " "
+		if name == active {
+			marker = "*"
+		}
+		profile := cfg.Profiles[name]
+		_, _ = fmt.Fprintf(a.out, "%s %-15s %s (owner: %s)\n", marker, name, profile.Server, profile.Owner)
+	}
+	return nil
+}
+
+func (a *App) deleteProfile(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: faynosync config delete-profile <profile>")
+	}
+
+	wasCurrent := false
+	_, err := config.Update(a.configPath, func(cfg *config.Config) error {
+		wasCurrent = cfg.Current == args[0]
+		return cfg.DeleteProfile(args[0])
+	})
+	if err != nil {
+		return err
+	}
+
+	a.logger.WithField("profile", args[0]).Info("Profile deleted")
+	if wasCurrent {
+		a.logger.Warn("Deleted the current profile, select another with: faynosync config use <profile>")
+	}
+	return nil
+}
+
+// readLine reads one line of input, or gives up when the Run context is
+// cancelled so Ctrl-C at a prompt does not wait for Enter.
+func (a *App) readLine() (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		line, err := a.br.ReadString('\n')
+		done <- result{line, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.line, r.err
+	case <-a.ctx.Done():
+		return "", context.Cause(a.ctx)
+	}
+}
+
 func (a *App) promptValue(key string) (string, error) {
-	_, _ = fmt.Fprintf(a.out, "Enter value for %s: ", key)
+	return a.prompt(fmt.Sprintf("Enter value for %s: ", key))
+}
 
-	line, err := a.br.ReadString('\n')
+// prompt prints question and returns the trimmed answer. A closed stdin
+// answers with an empty string.
+func (a *App) prompt(question string) (string, error) {
+	_, _ = fmt.Fprint(a.out, question)
+
+	line, err := a.readLine()
 	if err != nil && !errors.Is(err, io.EOF) {
 		return "", err
 	}
@@ -167,42 +528,105 @@ func (a *App) promptValue(key string) (string, error) {
 	return strings.TrimSpace(line), nil
 }
 
-func (a *App) promptValueWithDefault(key, defaultValue string) (string, error) {
-	_, _ = fmt.Fprintf(a.out, "Enter value for %s [%s]: ", key, defaultValue)
+// isTerminal reports whether r is an interactive terminal. Tests replace it.
+var isTerminal = func(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
 
-	line, err := a.br.ReadString('\n')
-	if err != nil && !errors.Is(err, io.EOF) {
+// promptSecret reads a value without echoing it when stdin is a terminal.
+// Otherwise it reads a line like the other prompts.
+func (a *App) promptSecret(key string) (string, error) {
+	file, ok := a.in.(*os.File)
+	if !ok || !isTerminal(a.in) {
+		return a.promptValue(key)
+	}
+
+	_, _ = fmt.Fprintf(a.out, "Enter value for %s: ", key)
+	raw, err := term.ReadPassword(int(file.Fd()))
+	_, _ = fmt.Fprintln(a.out)
+	if err != nil {
 		return "", err
 	}
+	return strings.TrimSpace(string(raw)), nil
+}
 
-	value := strings.TrimSpace(line)
-	if value == "" {
-		return defaultValue, nil
+func (a *App) confirm(question string) (bool, error) {
+	answer, err := a.prompt(question + " [y/N]: ")
+	if err != nil {
+		return false, err
 	}
 
-	return value, nil
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
 }
 
 func (a *App) printRootUsage() {
 	_, _ = fmt.Fprintln(a.out, `faynosync CLI
 
 Usage:
-  faynosync [--log-level <level>] <command>
+  faynosync [global flags] <command>
 
 Global flags:
   --log-level <level>    trace|debug|info|warn|error|fatal|panic (default: info)
+  --profile <name>       config profile to use (default: FAYNOSYNC_PROFILE or current)
+  --config <path>        config file to use (default: FAYNOSYNC_CONFIG or the XDG/legacy location)
+  --ca-bundle <path>     PEM file with extra CA certificates (overrides http.ca_bundle)
+  --client-cert <path>   client certificate for mutual TLS (overrides http.client_cert)
+  --client-key <path>    client key for mutual TLS (overrides http.client_key)
+  --tls-min-version <v>  minimum TLS version, 1.2 or 1.3 (overrides http.min_tls)
+  --insecure             skip TLS certificate verification (unsafe)
+  --proxy <url>          http, https, socks5 or socks5h proxy (overrides http.proxy)
+  --no-proxy <hosts>     comma-separated hosts that bypass the proxy (overrides http.no_proxy)
+  --limit-rate <rate>    upload bandwidth limit such as 500K or 10M per second (overrides http.limit_rate)
+  --timeout <d>          overall deadline per request (overrides http.timeout, default: none)
+  --connect-timeout <d>  TCP connect timeout (overrides http.connect_timeout, default: 30s)
+  --tls-timeout <d>      TLS handshake timeout (overrides http.tls_timeout, default: 10s)
+  --response-timeout <d> wait for response headers after sending (overrides http.response_timeout, default: 5m)
+  --stall-timeout <d>    abort when no bytes move (overrides http.stall_timeout, default: 1m)
 
 Commands:
-  faynosync init
-  faynosync config view
-  faynosync config set <server|owner> [value]
-  faynosync upload [flags]`)
+  faynosync init [flags]
+  faynosync config view [--show-origin]
+  faynosync config set|get|unset <key> [value]
+  faynosync config validate|migrate
+  faynosync config use|list|delete-profile
+  faynosync login [flags]
+  faynosync logout
+  faynosync auth exchange [flags]
+  faynosync whoami
+  faynosync env
+  faynosync upload [flags]
+  faynosync delete version [flags]
+  faynosync delete artifact [flags]
+  faynosync check [flags]
+  faynosync promote [flags]
+  faynosync rollback [flags]
+  faynosync prune [flags]`)
 }
 
 func (a *App) printConfigUsage() {
 	_, _ = fmt.Fprintln(a.out, `faynosync config commands
 
 Usage:
-  faynosync config view
-  faynosync config set <server|owner> [value]`)
+  faynosync config view [--show-origin]
+  faynosync config set <key> [value]
+  faynosync config get <key>
+  faynosync config unset <key>
+  faynosync config validate [path...]
+  faynosync config migrate [--dry-run]
+  faynosync config use <profile>
+  faynosync config list
+  faynosync config delete-profile <profile>
+
+--show-origin prints the effective settings, merged from the user config,
+the nearest .faynosync.yaml and the environment, with where each came from.
+
+Keys are dotted paths into the active profile, for example server,
+upload.channel, http.timeout or http.headers.<name>. Lists such as
+upload.files take comma-separated values.`)
 }
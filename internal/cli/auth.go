@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"faynoSync-cli/internal/config"
+)
+
+var errAuthHelp = errors.New("auth help requested")
+
+type exchangeFlags struct {
+	IDTokenFile string
+	IDTokenEnv  string
+	Endpoint    string
+	Save        bool
+}
+
+type exchangeRequest struct {
+	IDToken string `json:"id_token"`
+	Owner   string `json:"owner"`
+}
+
+type exchangeResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+func (a *App) runAuth(args []string) error {
+	if len(args) == 0 {
+		a.printAuthUsage()
+		return nil
+	}
+
+	switch args[0] {
+	case "exchange":
+		return a.authExchange(args[1:])
+	case "-h", "--help", "help":
+		a.printAuthUsage()
+		return nil
+	default:
+		return fmt.Errorf("unknown auth command: %s", args[0])
+	}
+}
+
+func (a *App) authExchange(args []string) error {
+	flags, err := parseExchangeFlags(args)
+	if err != nil {
+		if errors.Is(err, errAuthHelp) {
+			a.printAuthUsage()
+			return nil
+		}
+		return err
+	}
+
+	client, err := a.newPublicClient()
+	if err != nil {
+		return err
+	}
+
+	settings := client.oidc
+	if flags.IDTokenFile != "" || flags.IDTokenEnv != "" {
+		settings.TokenFile = flags.IDTokenFile
+		settings.TokenEnv = flags.IDTokenEnv
+	}
+	if flags.Endpoint != "" {
+		settings.Endpoint = flags.Endpoint
+	}
+
+	token, err := client.exchangeIDToken(settings)
+	if err != nil {
+		return err
+	}
+
+	if !flags.Save {
+		_, _ = fmt.Fprintln(a.out, token)
+		return nil
+	}
+
+	path, err := config.StoreToken(client.profile, client.server, token)
+	if err != nil {
+		return err
+	}
+
+	a.logger.WithFields(map[string]any{
+		"profile":     client.profile,
+		"credentials": path,
+	}).Info("Token exchanged and stored")
+	return nil
+}
+
+func (c *apiClient) exchangeIDToken(settings config.OIDC) (string, error) {
+	idToken, err := readIDToken(settings)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := strings.TrimSpace(settings.Endpoint)
+	if endpoint == "" {
+		endpoint = config.DefaultOIDCEndpoint
+	}
+
+	exchange := &apiClient{
+		ctx:     c.ctx,
+		profile: c.profile,
+		server:  c.server,
+		owner:   c.owner,
+		headers: c.headers,
+		http:    c.http,
+	}
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		exchange.server = ""
+	} else if !strings.HasPrefix(endpoint, "/") {
+		endpoint = "/" + endpoint
+	}
+
+	var resp exchangeResponse
+	if err := exchange.sendJSON(http.MethodPost, endpoint, nil, exchangeRequest{IDToken: idToken, Owner: c.owner}, &resp); err != nil {
+		return "", fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	token := strings.TrimSpace(resp.Token)
+	if token == "" {
+		token = strings.TrimSpace(resp.AccessToken)
+	}
+	if token == "" {
+		return "", errors.New("token exchange failed: server returned no token")
+	}
+	return token, nil
+}
+
+func readIDToken(settings config.OIDC) (string, error) {
+	if path := strings.TrimSpace(settings.TokenFile); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read OIDC token: %w", err)
+		}
+		if token := strings.TrimSpace(string(raw)); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("OIDC token file is empty: %s", path)
+	}
+
+	name := strings.TrimSpace(settings.TokenEnv)
+	if name == "" {
+		name = config.EnvIDToken
+	}
+	if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("no OIDC token: set %s, --id-token-env or --id-token-file", name)
+}
+
+func parseExchangeFlags(args []string) (exchangeFlags, error) {
+	var out exchangeFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return exchangeFlags{}, errAuthHelp
+		case arg == "--id-token-file":
+			val, consumed, err := requireValue(args, i, "--id-token-file")
+			if err != nil {
+				return exchangeFlags{}, err
+			}
+			out.IDTokenFile = val
+			i += consumed
+		case strings.HasPrefix(arg, "--id-token-file="):
+			out.IDTokenFile = strings.TrimPrefix(arg, "--id-token-file=")
+		case arg == "--id-token-env":
+			val, consumed, err := requireValue(args, i, "--id-token-env")
+			if err != nil {
+				return exchangeFlags{}, err
+			}
+			out.IDTokenEnv = val
+			i += consumed
+		case strings.HasPrefix(arg, "--id-token-env="):
+			out.IDTokenEnv = strings.TrimPrefix(arg, "--id-token-env=")
+		case arg == "--endpoint":
+			val, consumed, err := requireValue(args, i, "--endpoint")
+			if err != nil {
+				return exchangeFlags{}, err
+			}
+			out.Endpoint = val
+			i += consumed
+		case strings.HasPrefix(arg, "--endpoint="):
+			out.Endpoint = strings.TrimPrefix(arg, "--endpoint=")
+		case arg == "--save":
+			val, consumed, err := parseBoolValue(args, i, "--save")
+			if err != nil {
+				return exchangeFlags{}, err
+			}
+			out.Save = val
+			i += consumed
+		case strings.HasPrefix(arg, "--save="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--save="), "--save")
+			if err != nil {
+				return exchangeFlags{}, err
+			}
+			out.Save = val
+		default:
+			return exchangeFlags{}, fmt.Errorf("unknown auth exchange flag: %s", arg)
+		}
+	}
+
+	if out.IDTokenFile != "" && out.IDTokenEnv != "" {
+		return exchangeFlags{}, errors.New("use only one of --id-token-file or --id-token-env")
+	}
+
+	return out, nil
+}
+
+func (a *App) printAuthUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync auth
+
+Usage:
+  faynosync auth exchange [flags]
+
+Trades a CI-provided OIDC identity token for a faynoSync token. The token is
+printed to stdout unless --save stores it for the active profile.
+
+Exchange flags:
+  --id-token-file <path>   read the OIDC token from a file
+  --id-token-env <name>    read the OIDC token from an env var (default: FAYNOSYNC_ID_TOKEN)
+  --endpoint <path|url>    exchange endpoint (default: /auth/oidc/exchange)
+  --save                   store the token for the active profile, as login does`)
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"faynoSync-cli/internal/config"
+)
+
+func TestAuthExchangeTradesIDTokenFromFile(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+	fs.handle("/ci/exchange", func(w http.ResponseWriter, r *http.Request) {
+		var req exchangeRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.IDToken != "oidc-jwt" || req.Owner != "tester" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "faynosync-token"})
+	})
+
+	idTokenFile := filepath.Join(t.TempDir(), "id-token")
+	if err := os.WriteFile(idTokenFile, []byte("oidc-jwt\n"), 0o600); err != nil {
+		t.Fatalf("write id token: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"auth", "exchange", "--id-token-file", idTokenFile, "--endpoint", "/ci/exchange"}); err != nil {
+		t.Fatalf("auth exchange returned error: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "faynosync-token" {
+		t.Fatalf("expected token on stdout, got %q", out.String())
+	}
+}
+
+func TestAuthExchangeSavesTokenFromEnv(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+	t.Setenv("CI_JOB_JWT", "oidc-jwt")
+	fs.handle(config.DefaultOIDCEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "saved-token"})
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"auth", "exchange", "--id-token-env", "CI_JOB_JWT", "--save"}); err != nil {
+		t.Fatalf("auth exchange returned error: %v", err)
+	}
+
+	runtimeCfg, _, err := config.LoadRuntime("", "")
+	if err != nil || runtimeCfg.Token != "saved-token" {
+		t.Fatalf("expected saved token, got %+v, %v", runtimeCfg, err)
+	}
+}
+
+func TestLoadRuntimeReadsTokenFile(t *testing.T) {
+	newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("mounted-token\n"), 0o600); err != nil {
+		t.Fatalf("write token file: %v", err)
+	}
+	t.Setenv("FAYNOSYNC_TOKEN_FILE", tokenFile)
+
+	runtimeCfg, _, err := config.LoadRuntime("", "")
+	if err != nil || runtimeCfg.Token != "mounted-token" {
+		t.Fatalf("expected token from file, got %+v, %v", runtimeCfg, err)
+	}
+}
+
+func testJWT(exp time.Time) string {
+	enc := base64.RawURLEncoding
+	claims := fmt.Sprintf(`{"sub":"ci","exp":%d}`, exp.Unix())
+	return enc.EncodeToString([]byte(`{"alg":"HS256"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestExpiredTokenFailsBeforeAnyRequest(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", testJWT(time.Now().Add(-time.Hour)))
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"delete", "version", "--app", "myapp", "--version", "1.0.0", "--channel", "stable", "--yes"})
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected expiry error, got %v", err)
+	}
+	if got := fs.requestsTo("/search"); len(got) != 0 {
+		t.Fatalf("expected no requests with an expired token, got %d", len(got))
+	}
+}
+
+func storeTestToken(t *testing.T, server, token string) {
+	t.Helper()
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+	if _, err := config.StoreLogin(config.DefaultProfile, server, "ci", token); err != nil {
+		t.Fatalf("store token: %v", err)
+	}
+}
+
+func TestExpiredEnvTokenIsNotRefreshed(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", testJWT(time.Now().Add(-time.Hour)))
+	t.Setenv(config.EnvIDToken, "oidc-jwt")
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"whoami"})
+	if err == nil || !strings.Contains(err.Error(), "token from FAYNOSYNC_TOKEN expired") {
+		t.Fatalf("expected expiry error for the env token, got %v", err)
+	}
+	if got := fs.requestsTo(config.DefaultOIDCEndpoint); len(got) != 0 {
+		t.Fatalf("expected no exchange for an env token, got %d", len(got))
+	}
+}
+
+func TestExpiredStoredTokenPromptsForPassword(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	storeTestToken(t, fs.URL, testJWT(time.Now().Add(-time.Hour)))
+	terminal := isTerminal
+	isTerminal = func(io.Reader) bool { return true }
+	t.Cleanup(func() { isTerminal = terminal })
+
+	fresh := testJWT(time.Now().Add(time.Hour))
+	fs.handle("/login", func(w http.ResponseWriter, r *http.Request) {
+		var req loginRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Username != "ci" || req.Password != "secret" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(loginResponse{Token: fresh})
+	})
+	fs.handle("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+fresh {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"username":"ci"}`))
+	})
+
+	app := New(bytes.NewBufferString("secret\n"), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"whoami"}); err != nil {
+		t.Fatalf("expected login refresh, got %v", err)
+	}
+	if token, _ := config.StoredToken(config.DefaultProfile, fs.URL); token != fresh {
+		t.Fatalf("expected refreshed token to be stored, got %q", token)
+	}
+}
+
+func TestExpiredTokenIsRefreshedThroughOIDCExchange(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{{ID: "a", AppName: "myapp", Version: "1.0.0", Channel: "nightly"}})
+	storeTestToken(t, fs.URL, testJWT(time.Now().Add(-time.Hour)))
+	t.Setenv(config.EnvIDToken, "oidc-jwt")
+	fresh := testJWT(time.Now().Add(time.Hour))
+	fs.handle(config.DefaultOIDCEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": fresh})
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"delete", "version", "--app", "myapp", "--version", "1.0.0", "--channel", "nightly", "--yes"})
+	if err != nil {
+		t.Fatalf("expected refreshed token to be used, got %v", err)
+	}
+	if got := fs.requestsTo("/apps/delete"); len(got) != 1 {
+		t.Fatalf("expected one delete request, got %d", len(got))
+	}
+}
+
+func TestUnauthorizedResponseRefreshesAndRetriesOnce(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	storeTestToken(t, fs.URL, "stale-token")
+	t.Setenv(config.EnvIDToken, "oidc-jwt")
+	fs.handle(config.DefaultOIDCEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "fresh-token"})
+	})
+	fs.handle("/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": []versionRecord{}})
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"prune", "--app", "myapp", "--channel", "nightly", "--keep-last", "1", "--dry-run"}); err != nil {
+		t.Fatalf("expected retry with refreshed token, got %v", err)
+	}
+	if got := fs.requestsTo("/search"); len(got) != 2 {
+		t.Fatalf("expected exactly one retry, got %d search requests", len(got))
+	}
+	if got := fs.requestsTo(config.DefaultOIDCEndpoint); len(got) != 1 {
+		t.Fatalf("expected one refresh, got %d", len(got))
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var errCheckHelp = errors.New("check help requested")
+
+type checkFlags struct {
+	targetFlags
+	Owner string
+}
+
+type checkResult struct {
+	UpdateAvailable bool
+	Version         string
+	Critical        bool
+	Intermediate    bool
+	Changelog       string
+	URLs            map[string]string
+}
+
+func (a *App) runCheck(args []string) error {
+	flags, err := parseCheckFlags(args)
+	if err != nil {
+		if errors.Is(err, errCheckHelp) {
+			a.printCheckUsage()
+			return nil
+		}
+		return err
+	}
+	if err := flags.require("--app", "--version", "--channel", "--platform", "--arch"); err != nil {
+		return err
+	}
+
+	client, err := a.newPublicClient()
+	if err != nil {
+		return err
+	}
+
+	owner := strings.TrimSpace(flags.Owner)
+	if owner == "" {
+		owner = client.owner
+	}
+
+	query := url.Values{
+		"app_name": {flags.AppName},
+		"version":  {flags.Version},
+		"channel":  {flags.Channel},
+		"platform": {flags.Platform},
+		"arch":     {flags.Arch},
+		"owner":    {owner},
+	}
+	a.logger.WithField("query", query.Encode()).Debug("Checking for updates")
+
+	var raw map[string]any
+	if err := client.getJSON("/checkVersion", query, &raw); err != nil {
+		return err
+	}
+
+	a.printCheckResult(parseCheckResult(raw))
+	return nil
+}
+
+func parseCheckResult(raw map[string]any) checkResult {
+	out := checkResult{URLs: map[string]string{}}
+	for key, value := range raw {
+		switch {
+		case key == "update_available":
+			out.UpdateAvailable, _ = value.(bool)
+		case key == "critical":
+			out.Critical, _ = value.(bool)
+		case key == "is_intermediate_required":
+			out.Intermediate, _ = value.(bool)
+		case key == "version" || key == "latest_version":
+			out.Version, _ = value.(string)
+		case key == "changelog":
+			out.Changelog, _ = value.(string)
+		case strings.HasPrefix(key, "update_url"):
+			if link, ok := value.(string); ok && link != "" {
+				out.URLs[key] = link
+			}
+		}
+	}
+	return out
+}
+
+func (a *App) printCheckResult(result checkResult) {
+	if !result.UpdateAvailable {
+		_, _ = fmt.Fprintln(a.out, "update available: no")
+		return
+	}
+
+	_, _ = fmt.Fprintln(a.out, "update available: yes")
+	if result.Version != "" {
+		_, _ = fmt.Fprintf(a.out, "version:          %s\n", result.Version)
+	}
+	_, _ = fmt.Fprintf(a.out, "critical:         %t\n", result.Critical)
+	if result.Intermediate {
+		_, _ = fmt.Fprintln(a.out, "intermediate:     true")
+	}
+
+	keys := make([]string, 0, len(result.URLs))
+	for key := range result.URLs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		_, _ = fmt.Fprintln(a.out, "artifacts:        none")
+	} else {
+		_, _ = fmt.Fprintln(a.out, "artifacts:")
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(a.out, "  %s: %s\n", key, result.URLs[key])
+		}
+	}
+
+	if strings.TrimSpace(result.Changelog) != "" {
+		_, _ = fmt.Fprintf(a.out, "changelog:\n%s\n", strings.TrimRight(result.Changelog, "\n"))
+	}
+}
+
+func parseCheckFlags(args []string) (checkFlags, error) {
+	var out checkFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return checkFlags{}, errCheckHelp
+		case arg == "--owner":
+			val, consumed, err := requireValue(args, i, "--owner")
+			if err != nil {
+				return checkFlags{}, err
+			}
+			out.Owner = val
+			i += consumed
+		case strings.HasPrefix(arg, "--owner="):
+			out.Owner = strings.TrimPrefix(arg, "--owner=")
+		default:
+			consumed, ok, err := parseTargetFlag(args, i, &out.targetFlags)
+			if err != nil {
+				return checkFlags{}, err
+			}
+			if !ok {
+				return checkFlags{}, fmt.Errorf("unknown check flag: %s", arg)
+			}
+			i += consumed
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printCheckUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync check
+
+Usage:
+  faynosync check --app <name> --version <current> --channel <value> --platform <value> --arch <value> [--owner <name>]
+
+Asks the server whether a client running the given version would be offered an update.
+
+Check flags:
+  --app <name>
+  --version <value>      version the simulated client is running
+  --channel <value>
+  --platform <value>
+  --arch <value>
+  --owner <name>         defaults to the configured owner`)
+}
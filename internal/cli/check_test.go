@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckUsesConfiguredOwner(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	fs.handle("/checkVersion", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"update_available": true,
+			"critical":         true,
+			"update_url_deb":   "https://cdn/app-1.1.0.deb",
+			"update_url_rpm":   "https://cdn/app-1.1.0.rpm",
+		})
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(t.Context(), []string{"check", "--app", "myapp", "--version", "1.0.0", "--channel", "stable", "--platform", "linux", "--arch", "amd64"})
+	if err != nil {
+		t.Fatalf("check returned error: %v", err)
+	}
+
+	got := fs.requestsTo("/checkVersion")
+	if len(got) != 1 || !strings.Contains(got[0].Query, "owner=tester") {
+		t.Fatalf("expected owner from runtime config, got %+v", got)
+	}
+
+	for _, want := range []string{"update available: yes", "critical:         true", "update_url_deb: https://cdn/app-1.1.0.deb", "update_url_rpm: https://cdn/app-1.1.0.rpm"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCheckOwnerFlagOverridesConfig(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+	fs.handle("/checkVersion", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected anonymous request, got Authorization header")
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"update_available": false})
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(t.Context(), []string{"check", "--app=myapp", "--version=1.0.0", "--channel=stable", "--platform=linux", "--arch=amd64", "--owner=acme"})
+	if err != nil {
+		t.Fatalf("check returned error: %v", err)
+	}
+
+	got := fs.requestsTo("/checkVersion")
+	if len(got) != 1 || !strings.Contains(got[0].Query, "owner=acme") {
+		t.Fatalf("expected owner flag in query, got %+v", got)
+	}
+	if !strings.Contains(out.String(), "update available: no") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"faynoSync-cli/internal/config"
+)
+
+func TestConfigFlagSelectsConfigFile(t *testing.T) {
+	newFakeServer(t, nil)
+
+	path := filepath.Join(t.TempDir(), "ci", "faynosync.yaml")
+	t.Setenv("FAYNOSYNC_CONFIG", filepath.Join(t.TempDir(), "ignored.yaml"))
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"--config", path, "config", "set", "server", "https://isolated"}); err == nil {
+		t.Fatal("expected config set to fail before the file exists")
+	}
+
+	cfg := config.Config{Current: "default"}
+	cfg.SetProfile("default", config.Profile{Server: "https://isolated", Owner: "ci"})
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := config.SaveAt(path, cfg); err != nil {
+		t.Fatalf("save config: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app = New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"--config=" + path, "config", "view"}); err != nil {
+		t.Fatalf("config view returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "https://isolated") {
+		t.Fatalf("expected config from --config path, got:\n%s", out.String())
+	}
+}
+
+func TestHTTPSettingsAddHeadersAndRetry(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	backoff := retryBackoff
+	retryBackoff = 0
+	t.Cleanup(func() { retryBackoff = backoff })
+
+	cfg := config.Config{Current: "default"}
+	cfg.SetProfile("default", config.Profile{
+		Server: fs.URL,
+		Owner:  "tester",
+		HTTP:   config.HTTP{Retries: 2, Headers: map[string]string{"X-Team": "release"}},
+	})
+	if _, err := config.Init("", cfg); err != nil {
+		t.Fatalf("init config: %v", err)
+	}
+
+	calls := 0
+	fs.handle("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Team") != "release" {
+			t.Errorf("missing custom header, got %v", r.Header)
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"username":"ci","is_admin":true}`))
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"whoami"}); err != nil {
+		t.Fatalf("whoami returned error: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 2 retries, got %d calls", calls)
+	}
+}
+
+func TestConfigValidateReportsProblems(t *testing.T) {
+	newFakeServer(t, nil)
+	path, err := config.Path("")
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("current: default\nprofiles:\n  default:\n    http:\n      timeout: soon\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err = app.Run(t.Context(), []string{"config", "validate"})
+	if err == nil || !strings.Contains(err.Error(), "1 problem") {
+		t.Fatalf("expected validation failure, got %v", err)
+	}
+	if !strings.Contains(out.String(), "line 5: profiles.default.http.timeout") {
+		t.Fatalf("expected problem with line number, got:\n%s", out.String())
+	}
+
+	if err := app.Run(t.Context(), []string{"config", "set", "http.timeout", "45s"}); err != nil {
+		t.Fatalf("config set returned error: %v", err)
+	}
+	out.Reset()
+	if err := app.Run(t.Context(), []string{"config", "get", "http.timeout"}); err != nil {
+		t.Fatalf("config get returned error: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "45s" {
+		t.Fatalf("unexpected config get output: %q", out.String())
+	}
+	if err := app.Run(t.Context(), []string{"config", "validate"}); err != nil {
+		t.Fatalf("expected valid config after set, got %v", err)
+	}
+}
+
+func TestWarnsAboutWorldReadableConfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not meaningful on Windows")
+	}
+	newFakeServer(t, nil)
+	path, err := config.Init("", config.Default())
+	if err != nil {
+		t.Fatalf("init config: %v", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"config", "use", "default"}); err != nil {
+		t.Fatalf("config use returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "chmod 600 "+path) {
+		t.Fatalf("expected permission warning, got:\n%s", out.String())
+	}
+	if config.WorldReadable(path) {
+		t.Fatal("expected the save to tighten the config to 0600")
+	}
+}
+
+func TestConfigMigrateDryRunPrintsDiff(t *testing.T) {
+	newFakeServer(t, nil)
+	path, err := config.Path("")
+	if err != nil {
+		t.Fatalf("config path: %v", err)
+	}
+	legacy := "server: https://updates.example.com\nowner: acme\n"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"config", "migrate", "--dry-run"}); err != nil {
+		t.Fatalf("config migrate returned error: %v", err)
+	}
+	for _, want := range []string{"version 0 -> 1", "-server: https://updates.example.com", "+version: 1", "+profiles:"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in dry-run output:\n%s", want, out.String())
+		}
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != legacy {
+		t.Fatalf("dry run must not modify the file:\n%s", raw)
+	}
+
+	if err := app.Run(t.Context(), []string{"config", "migrate"}); err != nil {
+		t.Fatalf("config migrate returned error: %v", err)
+	}
+	if _, err := os.Stat(path + ".v0.bak"); err != nil {
+		t.Fatalf("expected backup file: %v", err)
+	}
+}
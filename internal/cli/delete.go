@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var errDeleteHelp = errors.New("delete help requested")
+
+type deleteFlags struct {
+	targetFlags
+	Package string
+	Yes     bool
+	Force   bool
+}
+
+type deleteArtifactRequest struct {
+	ID      string `json:"id"`
+	AppName string `json:"app_name"`
+	Version string `json:"version"`
+	Index   int    `json:"index"`
+}
+
+func (a *App) runDelete(args []string) error {
+	if len(args) == 0 {
+		a.printDeleteUsage()
+		return nil
+	}
+
+	switch args[0] {
+	case "version":
+		return a.deleteVersion(args[1:])
+	case "artifact":
+		return a.deleteArtifact(args[1:])
+	case "-h", "--help", "help":
+		a.printDeleteUsage()
+		return nil
+	default:
+		return fmt.Errorf("unknown delete command: %s", args[0])
+	}
+}
+
+func (a *App) deleteVersion(args []string) error {
+	flags, err := parseDeleteFlags(args)
+	if err != nil {
+		if errors.Is(err, errDeleteHelp) {
+			a.printDeleteUsage()
+			return nil
+		}
+		return err
+	}
+	if err := flags.require("--app", "--version", "--channel"); err != nil {
+		return err
+	}
+
+	client, err := a.newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	target, err := client.findVersion(flags.query(), flags.Version)
+	if err != nil {
+		return err
+	}
+
+	if target.Published && !flags.Force {
+		onChannel, err := client.searchVersions(versionQuery{AppName: flags.AppName, Channel: flags.Channel})
+		if err != nil {
+			return err
+		}
+		if countPublished(onChannel) <= 1 {
+			return fmt.Errorf("refusing to delete %s %s: it is the only published version on channel %q (use --force to override)", target.AppName, target.Version, target.Channel)
+		}
+	}
+
+	_, _ = fmt.Fprintln(a.out, "The following version and all of its artifacts will be deleted:")
+	writeVersionSummary(a.out, target)
+
+	if !flags.Yes {
+		ok, err := a.confirm("Delete this version?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted: nothing was deleted")
+		}
+	}
+
+	if err := client.deleteVersion(target.ID); err != nil {
+		return err
+	}
+
+	a.logger.WithFields(map[string]any{
+		"app":     target.AppName,
+		"version": target.Version,
+		"channel": target.Channel,
+		"id":      target.ID,
+	}).Info("Version deleted")
+	return nil
+}
+
+func (a *App) deleteArtifact(args []string) error {
+	flags, err := parseDeleteFlags(args)
+	if err != nil {
+		if errors.Is(err, errDeleteHelp) {
+			a.printDeleteUsage()
+			return nil
+		}
+		return err
+	}
+	if err := flags.require("--app", "--version", "--channel", "--platform", "--arch"); err != nil {
+		return err
+	}
+
+	client, err := a.newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	target, err := client.findVersion(versionQuery{AppName: flags.AppName, Channel: flags.Channel}, flags.Version)
+	if err != nil {
+		return err
+	}
+
+	matches := target.matchingArtifacts(flags.Platform, flags.Arch, flags.Package)
+	switch {
+	case len(matches) == 0:
+		return fmt.Errorf("no %s/%s artifact found for %s %s", flags.Platform, flags.Arch, target.AppName, target.Version)
+	case len(matches) > 1:
+		return fmt.Errorf("%d artifacts match %s/%s for %s %s, select one with --package", len(matches), flags.Platform, flags.Arch, target.AppName, target.Version)
+	}
+
+	index := matches[0]
+	artifact := target.Artifacts[index]
+	_, _ = fmt.Fprintf(a.out, "The following artifact of %s %s (channel %s) will be deleted:\n", target.AppName, target.Version, target.Channel)
+	writeArtifactLine(a.out, artifact)
+
+	if !flags.Yes {
+		ok, err := a.confirm("Delete this artifact?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted: nothing was deleted")
+		}
+	}
+
+	if err := client.deleteArtifact(target, index); err != nil {
+		return err
+	}
+
+	a.logger.WithFields(map[string]any{
+		"app":      target.AppName,
+		"version":  target.Version,
+		"platform": artifact.Platform,
+		"arch":     artifact.Arch,
+		"package":  artifact.Package,
+	}).Info("Artifact deleted")
+	return nil
+}
+
+func (c *apiClient) deleteVersion(id string) error {
+	_, err := c.do(http.MethodDelete, "/apps/delete", url.Values{"id": {id}}, nil)
+	return err
+}
+
+func (c *apiClient) deleteArtifact(record versionRecord, index int) error {
+	return c.sendJSON(http.MethodPost, "/artifact/delete", nil, deleteArtifactRequest{
+		ID:      record.ID,
+		AppName: record.AppName,
+		Version: record.Version,
+		Index:   index,
+	}, nil)
+}
+
+func countPublished(records []versionRecord) int {
+	count := 0
+	for _, record := range records {
+		if record.Published {
+			count++
+		}
+	}
+	return count
+}
+
+func parseDeleteFlags(args []string) (deleteFlags, error) {
+	var out deleteFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return deleteFlags{}, errDeleteHelp
+		case arg == "--package":
+			val, consumed, err := requireValue(args, i, "--package")
+			if err != nil {
+				return deleteFlags{}, err
+			}
+			out.Package = val
+			i += consumed
+		case strings.HasPrefix(arg, "--package="):
+			out.Package = strings.TrimPrefix(arg, "--package=")
+		case arg == "--yes" || arg == "-y":
+			val, consumed, err := parseBoolValue(args, i, "--yes")
+			if err != nil {
+				return deleteFlags{}, err
+			}
+			out.Yes = val
+			i += consumed
+		case strings.HasPrefix(arg, "--yes="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--yes="), "--yes")
+			if err != nil {
+				return deleteFlags{}, err
+			}
+			out.Yes = val
+		case arg == "--force":
+			val, consumed, err := parseBoolValue(args, i, "--force")
+			if err != nil {
+				return deleteFlags{}, err
+			}
+			out.Force = val
+			i += consumed
+		case strings.HasPrefix(arg, "--force="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--force="), "--force")
+			if err != nil {
+				return deleteFlags{}, err
+			}
+			out.Force = val
+		default:
+			consumed, ok, err := parseTargetFlag(args, i, &out.targetFlags)
+			if err != nil {
+				return deleteFlags{}, err
+			}
+			if !ok {
+				return deleteFlags{}, fmt.Errorf("unknown delete flag: %s", arg)
+			}
+			i += consumed
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printDeleteUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync delete
+
+Usage:
+  faynosync delete version --app <name> --version <value> --channel <value> [flags]
+  faynosync delete artifact --app <name> --version <value> --channel <value> --platform <value> --arch <value> [flags]
+
+Delete flags:
+  --app <name>
+  --version <value>
+  --channel <value>
+  --platform <value>     narrows the version lookup; required for artifact
+  --arch <value>         narrows the version lookup; required for artifact
+  --package <ext>        selects the artifact when several share platform/arch (e.g. .deb)
+  --yes, -y              skip the confirmation prompt
+  --force                allow deleting the only published version on a channel`)
+}
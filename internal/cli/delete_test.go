@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDeleteVersionRefusesOnlyPublishedVersion(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{ID: "a1", AppName: "myapp", Version: "1.0.0", Channel: "stable", Published: true},
+		{ID: "a2", AppName: "myapp", Version: "1.1.0", Channel: "stable"},
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"delete", "version", "--app", "myapp", "--version", "1.0.0", "--channel", "stable", "--yes"})
+	if err == nil || !strings.Contains(err.Error(), "only published version") {
+		t.Fatalf("expected only-published refusal, got %v", err)
+	}
+	if got := fs.requestsTo("/apps/delete"); len(got) != 0 {
+		t.Fatalf("expected no delete requests, got %d", len(got))
+	}
+}
+
+func TestDeleteVersionWithForceDeletesOnlyPublishedVersion(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{ID: "a1", AppName: "myapp", Version: "1.0.0", Channel: "stable", Published: true},
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"delete", "version", "--app=myapp", "--version=1.0.0", "--channel=stable", "--yes", "--force"})
+	if err != nil {
+		t.Fatalf("delete version returned error: %v", err)
+	}
+
+	got := fs.requestsTo("/apps/delete")
+	if len(got) != 1 || got[0].Query != "id=a1" {
+		t.Fatalf("unexpected delete requests: %+v", got)
+	}
+}
+
+func TestDeleteVersionAbortsWithoutConfirmation(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{ID: "a1", AppName: "myapp", Version: "1.0.0", Channel: "nightly"},
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBufferString("n\n"), out)
+	err := app.Run(t.Context(), []string{"delete", "version", "--app", "myapp", "--version", "1.0.0", "--channel", "nightly"})
+	if err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "version:      1.0.0") {
+		t.Fatalf("expected deletion plan in output, got:\n%s", out.String())
+	}
+	if got := fs.requestsTo("/apps/delete"); len(got) != 0 {
+		t.Fatalf("expected no delete requests, got %d", len(got))
+	}
+}
+
+func TestDeleteArtifactSelectsByPackage(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{
+			ID: "a1", AppName: "myapp", Version: "1.0.0", Channel: "stable", Published: true,
+			Artifacts: []artifactRecord{
+				{Link: "https://cdn/app.deb", Platform: "linux", Arch: "amd64", Package: ".deb"},
+				{Link: "https://cdn/app.rpm", Platform: "linux", Arch: "amd64", Package: ".rpm"},
+			},
+		},
+	})
+
+	app := New(bytes.NewBufferString("yes\n"), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{
+		"delete", "artifact",
+		"--app", "myapp", "--version", "1.0.0", "--channel", "stable",
+		"--platform", "linux", "--arch", "amd64", "--package", "rpm",
+	})
+	if err != nil {
+		t.Fatalf("delete artifact returned error: %v", err)
+	}
+
+	got := fs.requestsTo("/artifact/delete")
+	if len(got) != 1 || !strings.Contains(got[0].Body, `"index":1`) {
+		t.Fatalf("unexpected artifact delete requests: %+v", got)
+	}
+}
+
+func TestDeleteArtifactRequiresPackageWhenAmbiguous(t *testing.T) {
+	newFakeServer(t, []versionRecord{
+		{
+			ID: "a1", AppName: "myapp", Version: "1.0.0", Channel: "stable",
+			Artifacts: []artifactRecord{
+				{Platform: "linux", Arch: "amd64", Package: ".deb"},
+				{Platform: "linux", Arch: "amd64", Package: ".rpm"},
+			},
+		},
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{
+		"delete", "artifact",
+		"--app", "myapp", "--version", "1.0.0", "--channel", "stable",
+		"--platform", "linux", "--arch", "amd64", "--yes",
+	})
+	if err == nil || !strings.Contains(err.Error(), "--package") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+}
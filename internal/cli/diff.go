@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContext = 3
+
+type diffLine struct {
+	Op   byte // ' ', '-' or '+'
+	Text string
+}
+
+// lineDiff returns an edit script from before to after based on the longest
+// common subsequence of lines. Config files are small, so O(n*m) is fine.
+func lineDiff(before, after string) []diffLine {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, diffLine{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			out = append(out, diffLine{'+', b[j]})
+			j++
+		default:
+			out = append(out, diffLine{'-', a[i]})
+			i++
+		}
+	}
+	return out
+}
+
+// writeDiff prints changed lines with a few lines of context, separating
+// distant changes with "@@".
+func writeDiff(w io.Writer, fromName, toName string, lines []diffLine) {
+	_, _ = fmt.Fprintf(w, "--- %s\n+++ %s\n", fromName, toName)
+
+	show := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Op == ' ' {
+			continue
+		}
+		for k := max(0, i-diffContext); k <= min(len(lines)-1, i+diffContext); k++ {
+			show[k] = true
+		}
+	}
+
+	gap := false
+	for i, line := range lines {
+		if !show[i] {
+			gap = true
+			continue
+		}
+		if gap {
+			_, _ = fmt.Fprintln(w, "@@")
+			gap = false
+		}
+		_, _ = fmt.Fprintf(w, "%c%s\n", line.Op, line.Text)
+	}
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
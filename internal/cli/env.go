@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"faynoSync-cli/internal/config"
+)
+
+type uploadEnvVar struct {
+	Flag string
+	Env  string
+	// Key is the config key that supplies a default, if any.
+	Key string
+}
+
+// uploadEnvVars are read when the matching flag is not given. FAYNOSYNC_FILES
+// holds a comma-separated list of paths.
+var uploadEnvVars = []uploadEnvVar{
+	{Flag: "--app", Env: "FAYNOSYNC_APP", Key: "upload.app"},
+	{Flag: "--file", Env: "FAYNOSYNC_FILES", Key: "upload.files"},
+	{Flag: "--version", Env: "FAYNOSYNC_VERSION"},
+	{Flag: "--channel", Env: "FAYNOSYNC_CHANNEL", Key: "upload.channel"},
+	{Flag: "--platform", Env: "FAYNOSYNC_PLATFORM", Key: "upload.platform"},
+	{Flag: "--arch", Env: "FAYNOSYNC_ARCH", Key: "upload.arch"},
+	{Flag: "--publish", Env: "FAYNOSYNC_PUBLISH"},
+	{Flag: "--critical", Env: "FAYNOSYNC_CRITICAL"},
+	{Flag: "--intermediate", Env: "FAYNOSYNC_INTERMEDIATE"},
+	{Flag: "--changelog", Env: "FAYNOSYNC_CHANGELOG"},
+	{Flag: "--changelog-file", Env: "FAYNOSYNC_CHANGELOG_FILE"},
+	{Flag: "--changelog-stdin", Env: "FAYNOSYNC_CHANGELOG_STDIN"},
+	{Flag: "--preflight", Env: "FAYNOSYNC_PREFLIGHT"},
+	{Flag: "--content-length", Env: "FAYNOSYNC_CONTENT_LENGTH", Key: "upload.content_length"},
+}
+
+var changelogFlags = []string{"--changelog", "--changelog-file", "--changelog-stdin"}
+
+// applyUploadEnv runs env values through the flag parser for every flag that
+// was not given. A changelog flag disables all changelog env vars, so a
+// pipeline-wide FAYNOSYNC_CHANGELOG never conflicts with --changelog-file.
+func applyUploadEnv(out *uploadFlags, seen map[string]bool) error {
+	changelogGiven := false
+	for _, flag := range changelogFlags {
+		changelogGiven = changelogGiven || seen[flag]
+	}
+
+	for _, v := range uploadEnvVars {
+		value, ok := os.LookupEnv(v.Env)
+		if !ok || seen[v.Flag] || strings.TrimSpace(value) == "" {
+			continue
+		}
+		if changelogGiven && strings.HasPrefix(v.Flag, "--changelog") {
+			continue
+		}
+
+		args := []string{v.Flag + "=" + value}
+		if v.Flag == "--file" {
+			args = args[:0]
+			for _, path := range strings.Split(value, ",") {
+				if path = strings.TrimSpace(path); path != "" {
+					args = append(args, "--file="+path)
+				}
+			}
+		}
+		if _, err := parseUploadArgs(args, out); err != nil {
+			return fmt.Errorf("%s: %w", v.Env, err)
+		}
+	}
+	return nil
+}
+
+func (a *App) runEnv(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "--help", "help":
+			a.printEnvUsage()
+			return nil
+		default:
+			return fmt.Errorf("unknown env flag: %s", args[0])
+		}
+	}
+
+	settings, err := config.Resolve(a.configPath, a.profile)
+	if err != nil {
+		return err
+	}
+	path, err := config.Path(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SETTING\tENV\tVALUE\tSOURCE")
+	_, _ = fmt.Fprintf(w, "profile\t%s\t%s\t%s\n", config.EnvProfile, settings.Profile, a.profileSource(settings))
+	_, _ = fmt.Fprintf(w, "config\t%s\t%s\t%s\n", config.EnvConfig, path, a.configSource())
+	_, _ = fmt.Fprintf(w, "server\t%s\t%s\t%s\n", config.EnvURL, settings.Values.Server, settings.Origin("server"))
+	_, _ = fmt.Fprintf(w, "owner\t%s\t%s\t%s\n", config.EnvAccount, settings.Values.Owner, settings.Origin("owner"))
+
+	// The helper is not run here: env only reports where the token would
+	// come from.
+	token, source := "(not set)", "-"
+	if runtimeCfg, _, err := config.LoadServer(a.configPath, a.profile); err == nil {
+		if found, err := config.TokenSource(runtimeCfg); err == nil && found != "" {
+			token, source = "(set)", found
+		}
+	}
+	if source == config.TokenSourceHelper {
+		token = "(not fetched)"
+	}
+	_, _ = fmt.Fprintf(w, "token\t%s\t%s\t%s\n", config.EnvToken, token, source)
+
+	for _, v := range uploadEnvVars {
+		value, source := "", config.SourceDefault
+		switch {
+		case strings.TrimSpace(os.Getenv(v.Env)) != "":
+			value, source = os.Getenv(v.Env), "env "+v.Env
+		case v.Key != "":
+			value, _ = config.GetKey(settings.Values, v.Key)
+			source = settings.Origin(v.Key)
+		}
+		_, _ = fmt.Fprintf(w, "upload %s\t%s\t%s\t%s\n", v.Flag, v.Env, value, source)
+	}
+	return w.Flush()
+}
+
+func (a *App) profileSource(settings config.Settings) string {
+	switch {
+	case a.profile != "":
+		return config.SourceFlag
+	case strings.TrimSpace(os.Getenv(config.EnvProfile)) != "":
+		return "env " + config.EnvProfile
+	case settings.UserPath != "":
+		return "user " + settings.UserPath
+	default:
+		return config.SourceDefault
+	}
+}
+
+func (a *App) configSource() string {
+	switch {
+	case a.configPath != "":
+		return config.SourceFlag
+	case strings.TrimSpace(os.Getenv(config.EnvConfig)) != "":
+		return "env " + config.EnvConfig
+	default:
+		return config.SourceDefault
+	}
+}
+
+func (a *App) printEnvUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync env
+
+Usage:
+  faynosync env
+
+Prints every setting the CLI reads from the environment, with its effective
+value and where that value comes from. Tokens are never printed.`)
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"faynoSync-cli/internal/config"
+)
+
+func TestEnvShowsValuesAndSources(t *testing.T) {
+	newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_CHANNEL", "nightly")
+	t.Chdir(writeProject(t, "upload:\n  app: desktop\n"))
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"env"}); err != nil {
+		t.Fatalf("env returned error: %v", err)
+	}
+
+	lines := map[string]string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 2 && fields[0] == "upload" {
+			lines[fields[1]] = line
+		} else if len(fields) > 0 {
+			lines[fields[0]] = line
+		}
+	}
+
+	for setting, want := range map[string]string{
+		"--channel": "env FAYNOSYNC_CHANNEL",
+		"--app":     "project ",
+		"server":    "env FAYNOSYNC_URL",
+		"token":     "(set)",
+	} {
+		if !strings.Contains(lines[setting], want) {
+			t.Fatalf("expected %q for %s, got %q\n%s", want, setting, lines[setting], out.String())
+		}
+	}
+	if strings.Contains(out.String(), "test-token") {
+		t.Fatal("env must not print the token")
+	}
+}
+
+func TestEnvDoesNotRunCredentialHelper(t *testing.T) {
+	newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	cfg := config.Default()
+	cfg.SetProfile(config.DefaultProfile, config.Profile{CredentialHelper: "touch " + marker})
+	if _, err := config.Init("", cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"env"}); err != nil {
+		t.Fatalf("env returned error: %v", err)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("expected the credential helper not to run, got %v", err)
+	}
+	if !strings.Contains(out.String(), config.TokenSourceHelper) {
+		t.Fatalf("expected the helper to be reported as the token source:\n%s", out.String())
+	}
+}
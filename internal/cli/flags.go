@@ -6,8 +6,27 @@ import (
 	"strings"
 )
 
-func parseGlobalFlags(args []string) (string, []string, error) {
-	levelName := "info"
+type globalFlags struct {
+	LogLevel        string
+	Profile         string
+	Config          string
+	CABundle        string
+	ClientCert      string
+	ClientKey       string
+	MinTLS          string
+	Insecure        bool
+	Proxy           string
+	NoProxy         string
+	LimitRate       string
+	Timeout         string
+	ConnectTimeout  string
+	TLSTimeout      string
+	ResponseTimeout string
+	StallTimeout    string
+}
+
+func parseGlobalFlags(args []string) (globalFlags, []string, error) {
+	out := globalFlags{LogLevel: "info"}
 	i := 0
 
 	for i < len(args) {
@@ -19,19 +38,219 @@ func parseGlobalFlags(args []string) (string, []string, error) {
 		switch {
 		case arg == "--log-level":
 			if i+1 >= len(args) {
-				return "", nil, errors.New("missing value for --log-level")
+				return globalFlags{}, nil, errors.New("missing value for --log-level")
 			}
-			levelName = args[i+1]
+			out.LogLevel = args[i+1]
 			i += 2
 		case strings.HasPrefix(arg, "--log-level="):
-			levelName = strings.TrimPrefix(arg, "--log-level=")
+			out.LogLevel = strings.TrimPrefix(arg, "--log-level=")
+			i++
+		case arg == "--profile":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --profile")
+			}
+			out.Profile = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--profile="):
+			out.Profile = strings.TrimPrefix(arg, "--profile=")
+			i++
+		case arg == "--config":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --config")
+			}
+			out.Config = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--config="):
+			out.Config = strings.TrimPrefix(arg, "--config=")
+			i++
+		case arg == "--ca-bundle":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --ca-bundle")
+			}
+			out.CABundle = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--ca-bundle="):
+			out.CABundle = strings.TrimPrefix(arg, "--ca-bundle=")
+			i++
+		case arg == "--client-cert":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --client-cert")
+			}
+			out.ClientCert = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--client-cert="):
+			out.ClientCert = strings.TrimPrefix(arg, "--client-cert=")
+			i++
+		case arg == "--client-key":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --client-key")
+			}
+			out.ClientKey = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--client-key="):
+			out.ClientKey = strings.TrimPrefix(arg, "--client-key=")
+			i++
+		case arg == "--tls-min-version":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --tls-min-version")
+			}
+			out.MinTLS = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--tls-min-version="):
+			out.MinTLS = strings.TrimPrefix(arg, "--tls-min-version=")
+			i++
+		case arg == "--insecure":
+			out.Insecure = true
+			i++
+		case strings.HasPrefix(arg, "--insecure="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--insecure="), "--insecure")
+			if err != nil {
+				return globalFlags{}, nil, err
+			}
+			out.Insecure = val
+			i++
+		case arg == "--proxy":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --proxy")
+			}
+			out.Proxy = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--proxy="):
+			out.Proxy = strings.TrimPrefix(arg, "--proxy=")
+			i++
+		case arg == "--no-proxy":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --no-proxy")
+			}
+			out.NoProxy = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--no-proxy="):
+			out.NoProxy = strings.TrimPrefix(arg, "--no-proxy=")
+			i++
+		case arg == "--limit-rate":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --limit-rate")
+			}
+			out.LimitRate = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--limit-rate="):
+			out.LimitRate = strings.TrimPrefix(arg, "--limit-rate=")
+			i++
+		case arg == "--timeout":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --timeout")
+			}
+			out.Timeout = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--timeout="):
+			out.Timeout = strings.TrimPrefix(arg, "--timeout=")
+			i++
+		case arg == "--connect-timeout":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --connect-timeout")
+			}
+			out.ConnectTimeout = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--connect-timeout="):
+			out.ConnectTimeout = strings.TrimPrefix(arg, "--connect-timeout=")
+			i++
+		case arg == "--tls-timeout":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --tls-timeout")
+			}
+			out.TLSTimeout = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--tls-timeout="):
+			out.TLSTimeout = strings.TrimPrefix(arg, "--tls-timeout=")
+			i++
+		case arg == "--response-timeout":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --response-timeout")
+			}
+			out.ResponseTimeout = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--response-timeout="):
+			out.ResponseTimeout = strings.TrimPrefix(arg, "--response-timeout=")
+			i++
+		case arg == "--stall-timeout":
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, errors.New("missing value for --stall-timeout")
+			}
+			out.StallTimeout = args[i+1]
+			i += 2
+		case strings.HasPrefix(arg, "--stall-timeout="):
+			out.StallTimeout = strings.TrimPrefix(arg, "--stall-timeout=")
 			i++
 		case arg == "-h" || arg == "--help" || arg == "help":
-			return levelName, args[i:], nil
+			return out, args[i:], nil
 		default:
-			return "", nil, fmt.Errorf("unknown global flag: %s", arg)
+			return globalFlags{}, nil, fmt.Errorf("unknown global flag: %s", arg)
+		}
+	}
+
+	return out, args[i:], nil
+}
+
+type targetFlags struct {
+	AppName  string
+	Version  string
+	Channel  string
+	Platform string
+	Arch     string
+}
+
+func (t targetFlags) query() versionQuery {
+	return versionQuery{
+		AppName:  t.AppName,
+		Channel:  t.Channel,
+		Platform: t.Platform,
+		Arch:     t.Arch,
+	}
+}
+
+func (t targetFlags) require(names ...string) error {
+	values := map[string]string{
+		"--app":      t.AppName,
+		"--version":  t.Version,
+		"--channel":  t.Channel,
+		"--platform": t.Platform,
+		"--arch":     t.Arch,
+	}
+	for _, name := range names {
+		if strings.TrimSpace(values[name]) == "" {
+			return fmt.Errorf("%s is required", name)
+		}
+	}
+	return nil
+}
+
+func parseTargetFlag(args []string, idx int, target *targetFlags) (int, bool, error) {
+	fields := []struct {
+		name string
+		dst  *string
+	}{
+		{"--app", &target.AppName},
+		{"--version", &target.Version},
+		{"--channel", &target.Channel},
+		{"--platform", &target.Platform},
+		{"--arch", &target.Arch},
+	}
+
+	arg := strings.TrimSpace(args[idx])
+	for _, field := range fields {
+		switch {
+		case arg == field.name:
+			val, consumed, err := requireValue(args, idx, field.name)
+			if err != nil {
+				return 0, true, err
+			}
+			*field.dst = val
+			return consumed, true, nil
+		case strings.HasPrefix(arg, field.name+"="):
+			*field.dst = strings.TrimPrefix(arg, field.name+"=")
+			return 0, true, nil
 		}
 	}
 
-	return levelName, args[i:], nil
+	return 0, false, nil
 }
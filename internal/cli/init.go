@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"faynoSync-cli/internal/config"
+)
+
+var errInitHelp = errors.New("init help requested")
+
+const probeTimeout = 10 * time.Second
+
+type initFlags struct {
+	Server         string
+	Owner          string
+	Profile        string
+	NonInteractive bool
+	Force          bool
+	SkipVerify     bool
+}
+
+func (a *App) runInit(args []string) error {
+	flags, err := parseInitFlags(args)
+	if err != nil {
+		if errors.Is(err, errInitHelp) {
+			a.printInitUsage()
+			return nil
+		}
+		return err
+	}
+
+	path, err := config.Path(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg := config.Config{}
+	if _, err := os.Stat(path); err == nil {
+		cfg, _, err = config.LoadRaw(a.configPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	override := a.profile
+	if flags.Profile != "" {
+		override = flags.Profile
+	}
+	name := config.ActiveProfile(cfg, override)
+	if _, ok := cfg.Profiles[name]; ok && !flags.Force {
+		a.logger.WithFields(map[string]any{
+			"path":    path,
+			"profile": name,
+		}).Info("Config already exists, use --force to overwrite the profile")
+		return nil
+	}
+
+	// Without a terminal nobody can answer a prompt, as in Docker builds.
+	nonInteractive := flags.NonInteractive || !isTerminal(a.in)
+	server, err := a.initValue("server", flags.Server, nonInteractive)
+	if err != nil {
+		return err
+	}
+	a.logger.WithField("server", server).Debug("Server value")
+	owner, err := a.initValue("owner", flags.Owner, nonInteractive)
+	if err != nil {
+		return err
+	}
+	a.logger.WithField("owner", owner).Debug("Owner value")
+
+	server = strings.TrimRight(server, "/")
+	if err := (config.Profile{Server: server}).Validate(); err != nil {
+		return err
+	}
+	if flags.SkipVerify {
+		a.logger.WithField("server", server).Warn("Skipping server verification")
+	} else if err := a.probeServer(server, cfg.Profiles[name].HTTP); err != nil {
+		return fmt.Errorf("%w (use --skip-verify to save it anyway)", err)
+	}
+
+	// The update reloads the file under the lock, so a concurrent change to
+	// another profile is kept.
+	path, err = config.UpdateOrCreate(a.configPath, func(cfg *config.Config) error {
+		profile := cfg.Profiles[name]
+		profile.Server = server
+		profile.Owner = owner
+		cfg.SetProfile(name, profile)
+		if cfg.Current == "" {
+			cfg.Current = name
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	a.logger.WithFields(map[string]any{
+		"path":    path,
+		"profile": name,
+	}).Info("Config initialized")
+	return nil
+}
+
+func (a *App) initValue(key, flagValue string, nonInteractive bool) (string, error) {
+	if value := strings.TrimSpace(flagValue); value != "" {
+		return value, nil
+	}
+	if nonInteractive {
+		return "", fmt.Errorf("--%s is required with --non-interactive or when stdin is not a terminal", key)
+	}
+	value, err := a.promptValue(key)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s cannot be empty", key)
+	}
+	return value, nil
+}
+
+// healthResponse is the body of GET /health on a faynoSync server.
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// probeServer checks that server answers /health like a faynoSync server
+// and, for https, presents a certificate we trust. base holds the HTTP
+// settings of the profile being replaced, so its proxy, CA bundle and headers
+// apply to the probe too.
+func (a *App) probeServer(server string, base config.HTTP) error {
+	base.Timeout = probeTimeout.String()
+	settings := a.httpSettings(base)
+	a.logProxy(server, settings)
+	client, err := newHTTPClient(settings)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(a.ctx, http.MethodGet, server+"/health", nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range settings.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		if errors.As(err, &certErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) {
+			return fmt.Errorf("TLS verification failed for %s: %w", server, err)
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			return fmt.Errorf("server %s did not respond within %s", server, probeTimeout)
+		}
+		return fmt.Errorf("server %s is unreachable: %w", server, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("server %s health check returned status %d", server, resp.StatusCode)
+	}
+	var health healthResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&health); err != nil || strings.TrimSpace(health.Status) == "" {
+		return fmt.Errorf("server %s answered /health without a status, it does not look like a faynoSync server", server)
+	}
+
+	a.logger.WithFields(map[string]any{
+		"server": server,
+		"status": health.Status,
+	}).Info("Server is healthy")
+	return nil
+}
+
+func parseInitFlags(args []string) (initFlags, error) {
+	var out initFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return initFlags{}, errInitHelp
+		case arg == "--server":
+			val, consumed, err := requireValue(args, i, "--server")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.Server = val
+			i += consumed
+		case strings.HasPrefix(arg, "--server="):
+			out.Server = strings.TrimPrefix(arg, "--server=")
+		case arg == "--owner":
+			val, consumed, err := requireValue(args, i, "--owner")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.Owner = val
+			i += consumed
+		case strings.HasPrefix(arg, "--owner="):
+			out.Owner = strings.TrimPrefix(arg, "--owner=")
+		case arg == "--profile":
+			val, consumed, err := requireValue(args, i, "--profile")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.Profile = val
+			i += consumed
+		case strings.HasPrefix(arg, "--profile="):
+			out.Profile = strings.TrimPrefix(arg, "--profile=")
+		case arg == "--non-interactive":
+			val, consumed, err := parseBoolValue(args, i, "--non-interactive")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.NonInteractive = val
+			i += consumed
+		case strings.HasPrefix(arg, "--non-interactive="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--non-interactive="), "--non-interactive")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.NonInteractive = val
+		case arg == "--force":
+			val, consumed, err := parseBoolValue(args, i, "--force")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.Force = val
+			i += consumed
+		case strings.HasPrefix(arg, "--force="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--force="), "--force")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.Force = val
+		case arg == "--skip-verify":
+			val, consumed, err := parseBoolValue(args, i, "--skip-verify")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.SkipVerify = val
+			i += consumed
+		case strings.HasPrefix(arg, "--skip-verify="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--skip-verify="), "--skip-verify")
+			if err != nil {
+				return initFlags{}, err
+			}
+			out.SkipVerify = val
+		default:
+			return initFlags{}, fmt.Errorf("unknown init flag: %s", arg)
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printInitUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync init
+
+Usage:
+  faynosync init [flags]
+
+Creates the config file, or adds the active profile to an existing one.
+Values not given as flags are prompted for when stdin is a terminal, and are
+required otherwise. Before saving, the server is probed at /health and its
+TLS certificate is verified.
+
+Flags:
+  --server <url>        server URL
+  --owner <name>        owner name
+  --profile <name>      profile to create (default: the active profile)
+  --non-interactive     never prompt; --server and --owner are required
+  --force               overwrite an existing profile
+  --skip-verify         save without probing the server`)
+}
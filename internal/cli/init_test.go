@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"faynoSync-cli/internal/config"
+)
+
+func TestInitNonInteractiveProbesServer(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	fs.handle("/health", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"init", "--non-interactive", "--server", fs.URL + "/", "--owner", "acme", "--profile", "ci"}); err != nil {
+		t.Fatalf("init returned error: %v", err)
+	}
+	if len(fs.requestsTo("/health")) != 1 {
+		t.Fatal("expected init to probe /health")
+	}
+
+	cfg, _, err := config.LoadRaw("")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if got := cfg.Profiles["ci"]; got.Server != fs.URL || got.Owner != "acme" || cfg.Current != "ci" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+
+	err = app.Run(t.Context(), []string{"init", "--non-interactive", "--server", "https://other.example.com", "--owner", "other", "--profile", "ci", "--skip-verify"})
+	if err != nil {
+		t.Fatalf("init returned error: %v", err)
+	}
+	cfg, _, _ = config.LoadRaw("")
+	if cfg.Profiles["ci"].Owner != "acme" {
+		t.Fatal("expected existing profile to be kept without --force")
+	}
+
+	err = app.Run(t.Context(), []string{"init", "--non-interactive", "--server", "https://other.example.com", "--owner", "other", "--profile", "ci", "--skip-verify", "--force"})
+	if err != nil {
+		t.Fatalf("init --force returned error: %v", err)
+	}
+	cfg, _, _ = config.LoadRaw("")
+	if cfg.Profiles["ci"].Owner != "other" {
+		t.Fatal("expected --force to overwrite the profile")
+	}
+}
+
+func TestInitRefusesBadServers(t *testing.T) {
+	newFakeServer(t, nil)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	untrusted := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(untrusted.Close)
+
+	noHealth := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(noHealth.Close)
+
+	website := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html>Welcome</html>"))
+	}))
+	t.Cleanup(website.Close)
+
+	for name, tc := range map[string]struct {
+		args []string
+		want string
+	}{
+		"missing owner": {[]string{"--server", "https://updates.example.com"}, "--owner is required"},
+		"malformed":     {[]string{"--server", "updates.example.com", "--owner", "acme"}, "server:"},
+		"unreachable":   {[]string{"--server", closedURL, "--owner", "acme"}, "unreachable"},
+		"untrusted tls": {[]string{"--server", untrusted.URL, "--owner", "acme"}, "TLS verification failed"},
+		"no health":     {[]string{"--server", noHealth.URL, "--owner", "acme"}, "returned status 404"},
+		"other website": {[]string{"--server", website.URL, "--owner", "acme"}, "does not look like a faynoSync server"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+			err := app.Run(t.Context(), append([]string{"init", "--non-interactive"}, tc.args...))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected error containing %q, got %v", tc.want, err)
+			}
+			if _, _, err := config.LoadRaw(""); err == nil {
+				t.Fatal("expected no config to be written")
+			}
+		})
+	}
+}
+
+func TestInitWithoutTerminalRequiresServer(t *testing.T) {
+	newFakeServer(t, nil)
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"init", "--owner", "acme"})
+	if err == nil || !strings.Contains(err.Error(), "--server is required") {
+		t.Fatalf("expected missing --server error, got %v", err)
+	}
+	if _, _, err := config.LoadRaw(""); err == nil {
+		t.Fatal("expected no config to be written")
+	}
+}
+
+func TestInitProbeUsesProfileHTTPSettings(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	var team string
+	fs.handle("/health", func(w http.ResponseWriter, r *http.Request) {
+		team = r.Header.Get("X-Team")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	})
+
+	cfg := config.Default()
+	cfg.SetProfile(config.DefaultProfile, config.Profile{
+		Server: "https://old.example.com",
+		Owner:  "acme",
+		HTTP:   config.HTTP{Headers: map[string]string{"X-Team": "release"}},
+	})
+	if _, err := config.Init("", cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"init", "--non-interactive", "--server", fs.URL, "--owner", "acme", "--force"}); err != nil {
+		t.Fatalf("init returned error: %v", err)
+	}
+	if team != "release" {
+		t.Fatalf("expected the probe to send the profile headers, got X-Team %q", team)
+	}
+}
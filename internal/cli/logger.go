@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"strings"
@@ -21,6 +22,7 @@ func New(in io.Reader, out io.Writer) *App {
 	})
 
 	return &App{
+		ctx:    context.Background(),
 		in:     in,
 		out:    out,
 		br:     bufio.NewReader(in),
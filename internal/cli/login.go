@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"faynoSync-cli/internal/config"
+)
+
+var errLoginHelp = errors.New("login help requested")
+
+type loginFlags struct {
+	Username      string
+	PasswordStdin bool
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+func (a *App) runLogin(args []string) error {
+	flags, err := parseLoginFlags(args)
+	if err != nil {
+		if errors.Is(err, errLoginHelp) {
+			a.printLoginUsage()
+			return nil
+		}
+		return err
+	}
+
+	client, err := a.newPublicClient()
+	if err != nil {
+		return err
+	}
+
+	username := strings.TrimSpace(flags.Username)
+	if username == "" {
+		if flags.PasswordStdin {
+			return errors.New("--username is required with --password-stdin")
+		}
+		username, err = a.promptValue("username")
+		if err != nil {
+			return err
+		}
+	}
+	if username == "" {
+		return errors.New("username cannot be empty")
+	}
+
+	var password string
+	if flags.PasswordStdin {
+		raw, err := io.ReadAll(a.br)
+		if err != nil {
+			return err
+		}
+		password = strings.TrimRight(string(raw), "\r\n")
+	} else {
+		password, err = a.promptSecret("password")
+		if err != nil {
+			return err
+		}
+	}
+	if password == "" {
+		return errors.New("password cannot be empty")
+	}
+
+	token, err := client.login(username, password)
+	if err != nil {
+		return err
+	}
+
+	path, err := config.StoreLogin(client.profile, client.server, username, token)
+	if err != nil {
+		return err
+	}
+
+	a.logger.WithFields(map[string]any{
+		"profile":     client.profile,
+		"username":    username,
+		"credentials": path,
+	}).Info("Login succeeded")
+	return nil
+}
+
+func (c *apiClient) login(username, password string) (string, error) {
+	var resp loginResponse
+	if err := c.sendJSON(http.MethodPost, "/login", nil, loginRequest{Username: username, Password: password}, &resp); err != nil {
+		return "", fmt.Errorf("login failed: %w", err)
+	}
+	token := strings.TrimSpace(resp.Token)
+	if token == "" {
+		return "", errors.New("login failed: server returned no token")
+	}
+	return token, nil
+}
+
+// loginRefresher logs in again as the user saved by faynosync login once the
+// stored token has expired. The password has to be typed, so it is only
+// offered when stdin is a terminal.
+func (a *App) loginRefresher(client *apiClient) func() (string, error) {
+	cred, err := config.StoredCredential(client.profile, client.server)
+	if err != nil || cred.Username == "" || !isTerminal(a.in) {
+		return nil
+	}
+
+	return func() (string, error) {
+		a.logger.WithFields(map[string]any{
+			"profile":  client.profile,
+			"username": cred.Username,
+		}).Warn("Stored token is no longer valid, log in again")
+		password, err := a.promptSecret("password for " + cred.Username)
+		if err != nil {
+			return "", err
+		}
+
+		login := *client
+		login.token = ""
+		login.refresh = nil
+		token, err := login.login(cred.Username, password)
+		if err != nil {
+			return "", err
+		}
+		if _, err := config.StoreLogin(client.profile, client.server, cred.Username, token); err != nil {
+			a.logger.WithError(err).Warn("Could not store refreshed token")
+		}
+		return token, nil
+	}
+}
+
+func (a *App) runLogout(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "--help", "help":
+			a.printLoginUsage()
+			return nil
+		default:
+			return fmt.Errorf("unknown logout flag: %s", args[0])
+		}
+	}
+
+	// Logging out must work even when the profile only exists in env vars.
+	runtimeCfg, _, err := config.LoadServer(a.configPath, a.profile)
+	if err != nil {
+		return err
+	}
+	profile := runtimeCfg.Profile
+
+	removed, err := config.RemoveToken(profile, runtimeCfg.Server)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		a.logger.WithField("profile", profile).Info("No stored token for profile")
+		return nil
+	}
+
+	a.logger.WithField("profile", profile).Info("Logged out")
+	return nil
+}
+
+func parseLoginFlags(args []string) (loginFlags, error) {
+	var out loginFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return loginFlags{}, errLoginHelp
+		case arg == "--username":
+			val, consumed, err := requireValue(args, i, "--username")
+			if err != nil {
+				return loginFlags{}, err
+			}
+			out.Username = val
+			i += consumed
+		case strings.HasPrefix(arg, "--username="):
+			out.Username = strings.TrimPrefix(arg, "--username=")
+		case arg == "--password-stdin":
+			val, consumed, err := parseBoolValue(args, i, "--password-stdin")
+			if err != nil {
+				return loginFlags{}, err
+			}
+			out.PasswordStdin = val
+			i += consumed
+		case strings.HasPrefix(arg, "--password-stdin="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--password-stdin="), "--password-stdin")
+			if err != nil {
+				return loginFlags{}, err
+			}
+			out.PasswordStdin = val
+		default:
+			return loginFlags{}, fmt.Errorf("unknown login flag: %s", arg)
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printLoginUsage() {
+	path, err := config.CredentialsPath()
+	if err != nil {
+		path = "the credentials file"
+	}
+	_, _ = fmt.Fprintf(a.out, `faynosync login
+
+Usage:
+  faynosync login [--username <name>] [--password-stdin]
+  faynosync logout
+
+Exchanges a username and password for a token and stores it for the active
+profile in %s (mode 0600). FAYNOSYNC_TOKEN still
+takes precedence over the stored token.
+
+Login flags:
+  --username <name>      prompted for when omitted
+  --password-stdin       read the password from stdin instead of prompting
+`, path)
+}
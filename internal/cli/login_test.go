@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"faynoSync-cli/internal/config"
+)
+
+func TestLoginStoresTokenForProfile(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", "")
+	fs.handle("/login", func(w http.ResponseWriter, r *http.Request) {
+		var req loginRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Username != "admin" || req.Password != "s3cret pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "jwt-from-login"})
+	})
+
+	app := New(bytes.NewBufferString("s3cret pass\n"), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"--profile", "staging", "login", "--username", "admin", "--password-stdin"}); err != nil {
+		t.Fatalf("login returned error: %v", err)
+	}
+
+	path, err := config.CredentialsPath()
+	if err != nil {
+		t.Fatalf("credentials path: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat credentials: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 credentials file, got %v", info.Mode().Perm())
+	}
+
+	runtimeCfg, _, err := config.LoadRuntime("", "staging")
+	if err != nil || runtimeCfg.Token != "jwt-from-login" {
+		t.Fatalf("expected stored token, got %+v, %v", runtimeCfg, err)
+	}
+
+	t.Setenv("FAYNOSYNC_URL", "https://elsewhere.example.com")
+	if _, _, err := config.LoadRuntime("", "staging"); err == nil || !strings.Contains(err.Error(), "issued by "+fs.URL) {
+		t.Fatalf("expected the stored token to be refused for another server, got %v", err)
+	}
+	t.Setenv("FAYNOSYNC_URL", fs.URL)
+
+	t.Setenv("FAYNOSYNC_TOKEN", "env-token")
+	runtimeCfg, _, err = config.LoadRuntime("", "staging")
+	if err != nil || runtimeCfg.Token != "env-token" {
+		t.Fatalf("expected env token to take precedence, got %+v, %v", runtimeCfg, err)
+	}
+
+	if err := app.Run(t.Context(), []string{"--profile", "staging", "logout"}); err != nil {
+		t.Fatalf("logout returned error: %v", err)
+	}
+	if token, _ := config.StoredToken("staging", fs.URL); token != "" {
+		t.Fatalf("expected token to be removed, got %q", token)
+	}
+}
+
+func TestLoginPromptsForUsernameAndPassword(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	fs.handle("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid credentials"}`))
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBufferString("admin\nwrong\n"), out)
+	err := app.Run(t.Context(), []string{"login"})
+	if err == nil || !strings.Contains(err.Error(), "invalid credentials") {
+		t.Fatalf("expected login failure, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Enter value for username: ") || !strings.Contains(out.String(), "Enter value for password: ") {
+		t.Fatalf("expected prompts in output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProject(t *testing.T, content string) string {
+	t.Helper()
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, ".faynosync.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+	return repo
+}
+
+func TestUploadUsesProjectDefaults(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	var data string
+	fs.handle("/upload", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+		}
+		data = r.FormValue("data")
+		if got := len(r.MultipartForm.File["file"]); got != 2 {
+			t.Errorf("expected 2 files from upload.files, got %d", got)
+		}
+		_, _ = w.Write([]byte(`{"uploaded_id":"1"}`))
+	})
+
+	repo := writeProject(t, "upload:\n  app: desktop\n  channel: nightly\n  files:\n    - dist/*.bin\n")
+	if err := os.MkdirAll(filepath.Join(repo, "dist"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for _, name := range []string{"a.bin", "b.bin", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(repo, "dist", name), []byte(name), 0o644); err != nil {
+			t.Fatalf("write artifact: %v", err)
+		}
+	}
+	sub := filepath.Join(repo, "src")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Chdir(sub)
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"upload", "--version", "1.0.0", "--channel", "beta"}); err != nil {
+		t.Fatalf("upload returned error: %v", err)
+	}
+
+	if !strings.Contains(data, `"app_name":"desktop"`) {
+		t.Fatalf("expected app from project config, got %s", data)
+	}
+	if !strings.Contains(data, `"channel":"beta"`) {
+		t.Fatalf("expected --channel to win over project config, got %s", data)
+	}
+}
+
+func TestUploadFailsWhenProjectPatternMatchesNothing(t *testing.T) {
+	newFakeServer(t, nil)
+	t.Chdir(writeProject(t, "upload:\n  app: desktop\n  files:\n    - dist/*.bin\n"))
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"upload", "--version", "1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "no files match") {
+		t.Fatalf("expected unmatched pattern error, got %v", err)
+	}
+}
+
+func TestConfigViewShowOrigin(t *testing.T) {
+	newFakeServer(t, nil)
+	repo := writeProject(t, "upload:\n  app: desktop\n")
+	t.Chdir(repo)
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"config", "view", "--show-origin"}); err != nil {
+		t.Fatalf("config view returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		"env FAYNOSYNC_URL",
+		"env FAYNOSYNC_ACCOUNT",
+		"upload.app",
+		"project " + filepath.Join(repo, ".faynosync.yaml"),
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var errPromoteHelp = errors.New("promote help requested")
+
+type promoteFlags struct {
+	targetFlags
+	From   string
+	To     string
+	DryRun bool
+}
+
+type artifactGroup struct {
+	Platform  string
+	Arch      string
+	Artifacts []artifactRecord
+}
+
+func (a *App) runPromote(args []string) error {
+	flags, err := parsePromoteFlags(args)
+	if err != nil {
+		if errors.Is(err, errPromoteHelp) {
+			a.printPromoteUsage()
+			return nil
+		}
+		return err
+	}
+	if err := flags.require("--app", "--version"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(flags.From) == "" || strings.TrimSpace(flags.To) == "" {
+		return errors.New("--from and --to are required")
+	}
+	if flags.From == flags.To {
+		return errors.New("--from and --to must be different channels")
+	}
+
+	client, err := a.newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	source, err := client.findVersion(versionQuery{AppName: flags.AppName, Channel: flags.From}, flags.Version)
+	if err != nil {
+		return err
+	}
+
+	existing, err := client.searchVersions(versionQuery{AppName: flags.AppName, Channel: flags.To})
+	if err != nil {
+		return err
+	}
+	for _, record := range existing {
+		if record.Version == source.Version {
+			return fmt.Errorf("%s %s already exists on channel %q", source.AppName, source.Version, flags.To)
+		}
+	}
+
+	groups := groupArtifacts(source, flags.Platform, flags.Arch)
+	if len(groups) == 0 {
+		return fmt.Errorf("%s %s on channel %q has no matching artifacts to promote", source.AppName, source.Version, flags.From)
+	}
+
+	if flags.DryRun {
+		_, _ = fmt.Fprintf(a.out, "Dry run: would promote %s %s from %s to %s\n", source.AppName, source.Version, flags.From, flags.To)
+		writePromotePlan(a.out, source, groups)
+		return nil
+	}
+
+	changelog := source.changes()
+	var uploadedIDs []string
+	for i, group := range groups {
+		parts := make([]uploadPart, 0, len(group.Artifacts))
+		for _, artifact := range group.Artifacts {
+			link := artifact.Link
+			parts = append(parts, uploadPart{
+				Name: artifactFileName(source, artifact),
+				Open: func() (io.ReadCloser, error) {
+					return client.download(link)
+				},
+			})
+		}
+
+		a.logger.WithFields(map[string]any{
+			"platform":  group.Platform,
+			"arch":      group.Arch,
+			"artifacts": len(group.Artifacts),
+		}).Debug("Promoting artifact group")
+		if client.limiter != nil {
+			a.logger.WithField("limit_rate", client.limiter.label+"/s").Debug("Uploading with bandwidth limit")
+		}
+
+		respBody, err := client.upload(parts, uploadData{
+			AppName:      source.AppName,
+			Version:      source.Version,
+			Channel:      flags.To,
+			Publish:      source.Published,
+			Critical:     source.Critical,
+			Intermediate: source.Intermediate,
+			Platform:     group.Platform,
+			Arch:         group.Arch,
+			Changelog:    changelog,
+		})
+		if err != nil {
+			err = fmt.Errorf("promote %s/%s: %w", group.Platform, group.Arch, err)
+			return a.partialPromotion(source, flags.To, groups[:i], uploadedIDs, err)
+		}
+		if id := extractUploadedID(respBody); id != "" {
+			uploadedIDs = append(uploadedIDs, id)
+		}
+	}
+
+	_, _ = fmt.Fprintf(a.out, "Promoted %s %s from %s to %s\n", source.AppName, source.Version, flags.From, flags.To)
+	writePromotePlan(a.out, source, groups)
+
+	a.logger.WithFields(map[string]any{
+		"app":         source.AppName,
+		"version":     source.Version,
+		"from":        flags.From,
+		"to":          flags.To,
+		"uploaded_id": strings.Join(uploadedIDs, ","),
+	}).Info("Promotion completed")
+	return nil
+}
+
+// partialPromotion reports the groups that reached the target channel before
+// err. The version then exists there incomplete, and promoting again is
+// refused until it is deleted.
+func (a *App) partialPromotion(source versionRecord, channel string, done []artifactGroup, uploadedIDs []string, err error) error {
+	if len(done) == 0 {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.out, "Partially promoted %s %s to %s:\n", source.AppName, source.Version, channel)
+	for _, group := range done {
+		for _, artifact := range group.Artifacts {
+			writeArtifactLine(a.out, artifact)
+		}
+	}
+	a.logger.WithFields(map[string]any{
+		"app":         source.AppName,
+		"version":     source.Version,
+		"to":          channel,
+		"uploaded_id": strings.Join(uploadedIDs, ","),
+	}).Warn("Promotion stopped after some artifact groups were uploaded")
+
+	return fmt.Errorf("%w; %s %s is incomplete on channel %q, remove it with: faynosync delete version --app %s --version %s --channel %s, then promote again",
+		err, source.AppName, source.Version, channel, source.AppName, source.Version, channel)
+}
+
+func groupArtifacts(record versionRecord, platform, arch string) []artifactGroup {
+	var groups []artifactGroup
+	index := map[string]int{}
+	for _, i := range record.matchingArtifacts(platform, arch, "") {
+		artifact := record.Artifacts[i]
+		key := artifact.Platform + "/" + artifact.Arch
+		pos, ok := index[key]
+		if !ok {
+			pos = len(groups)
+			index[key] = pos
+			groups = append(groups, artifactGroup{Platform: artifact.Platform, Arch: artifact.Arch})
+		}
+		groups[pos].Artifacts = append(groups[pos].Artifacts, artifact)
+	}
+	return groups
+}
+
+func writePromotePlan(w io.Writer, source versionRecord, groups []artifactGroup) {
+	_, _ = fmt.Fprintf(w, "  published:    %t\n", source.Published)
+	_, _ = fmt.Fprintf(w, "  critical:     %t\n", source.Critical)
+	_, _ = fmt.Fprintf(w, "  intermediate: %t\n", source.Intermediate)
+	_, _ = fmt.Fprintf(w, "  changelog:    %d bytes\n", len(source.changes()))
+	_, _ = fmt.Fprintln(w, "  artifacts:")
+	for _, group := range groups {
+		for _, artifact := range group.Artifacts {
+			writeArtifactLine(w, artifact)
+		}
+	}
+}
+
+func artifactFileName(record versionRecord, artifact artifactRecord) string {
+	if parsed, err := url.Parse(artifact.Link); err == nil {
+		if name := path.Base(parsed.Path); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	return fmt.Sprintf("%s-%s-%s-%s%s", record.AppName, record.Version, artifact.Platform, artifact.Arch, artifact.Package)
+}
+
+func (c *apiClient) download(link string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sameHost(link, c.server) {
+		for name, value := range c.headers {
+			req.Header.Set(name, value)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("download %s: %w", link, &apiError{
+			Status: resp.StatusCode,
+			Body:   strings.TrimSpace(string(body)),
+		})
+	}
+
+	return resp.Body, nil
+}
+
+func sameHost(a, b string) bool {
+	left, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	right, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(left.Host, right.Host)
+}
+
+func parsePromoteFlags(args []string) (promoteFlags, error) {
+	var out promoteFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return promoteFlags{}, errPromoteHelp
+		case arg == "--from":
+			val, consumed, err := requireValue(args, i, "--from")
+			if err != nil {
+				return promoteFlags{}, err
+			}
+			out.From = val
+			i += consumed
+		case strings.HasPrefix(arg, "--from="):
+			out.From = strings.TrimPrefix(arg, "--from=")
+		case arg == "--to":
+			val, consumed, err := requireValue(args, i, "--to")
+			if err != nil {
+				return promoteFlags{}, err
+			}
+			out.To = val
+			i += consumed
+		case strings.HasPrefix(arg, "--to="):
+			out.To = strings.TrimPrefix(arg, "--to=")
+		case arg == "--dry-run":
+			val, consumed, err := parseBoolValue(args, i, "--dry-run")
+			if err != nil {
+				return promoteFlags{}, err
+			}
+			out.DryRun = val
+			i += consumed
+		case strings.HasPrefix(arg, "--dry-run="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--dry-run="), "--dry-run")
+			if err != nil {
+				return promoteFlags{}, err
+			}
+			out.DryRun = val
+		case arg == "--channel" || strings.HasPrefix(arg, "--channel="):
+			return promoteFlags{}, errors.New("promote uses --from and --to instead of --channel")
+		default:
+			consumed, ok, err := parseTargetFlag(args, i, &out.targetFlags)
+			if err != nil {
+				return promoteFlags{}, err
+			}
+			if !ok {
+				return promoteFlags{}, fmt.Errorf("unknown promote flag: %s", arg)
+			}
+			i += consumed
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printPromoteUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync promote
+
+Usage:
+  faynosync promote --app <name> --version <value> --from <channel> --to <channel> [flags]
+
+Copies a version with its metadata, changelog and artifacts to another channel.
+The server has no copy endpoint, so artifacts are streamed from their download
+links straight into a new upload, one per platform/arch pair. If a later pair
+fails, the pairs already promoted are listed and the incomplete version has to
+be deleted before promoting again.
+
+Promote flags:
+  --app <name>
+  --version <value>
+  --from <channel>
+  --to <channel>
+  --platform <value>     promote only artifacts for this platform
+  --arch <value>         promote only artifacts for this arch
+  --dry-run              print what would be promoted without uploading`)
+}
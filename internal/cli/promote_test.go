@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPromoteStreamsArtifactsToTargetChannel(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	fs.records = []versionRecord{
+		{
+			ID: "b1", AppName: "myapp", Version: "1.2.3", Channel: "beta", Published: true, Critical: true,
+			Changelog: []changelogRecord{{Version: "1.2.3", Changes: "- fixed crash"}},
+			Artifacts: []artifactRecord{
+				{Link: fs.URL + "/download/myapp.deb", Platform: "linux", Arch: "amd64", Package: ".deb"},
+				{Link: fs.URL + "/download/myapp.exe", Platform: "windows", Arch: "amd64", Package: ".exe"},
+			},
+		},
+	}
+	fs.handle("/download/myapp.deb", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("deb-bytes"))
+	})
+	fs.handle("/download/myapp.exe", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("exe-bytes"))
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(t.Context(), []string{"promote", "--app", "myapp", "--version", "1.2.3", "--from", "beta", "--to", "stable"})
+	if err != nil {
+		t.Fatalf("promote returned error: %v", err)
+	}
+
+	uploads := fs.requestsTo("/upload")
+	if len(uploads) != 2 {
+		t.Fatalf("expected one upload per platform/arch group, got %d", len(uploads))
+	}
+	first := uploads[0].Body
+	for _, want := range []string{`"channel":"stable"`, `"platform":"linux"`, `"critical":true`, `"changelog":"- fixed crash"`, "deb-bytes", `filename="myapp.deb"`} {
+		if !strings.Contains(first, want) {
+			t.Fatalf("expected %q in upload body:\n%s", want, first)
+		}
+	}
+	if !strings.Contains(out.String(), "Promoted myapp 1.2.3 from beta to stable") {
+		t.Fatalf("expected summary in output:\n%s", out.String())
+	}
+}
+
+func TestPromoteReportsGroupsBeforeFailure(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	fs.records = []versionRecord{
+		{
+			ID: "b1", AppName: "myapp", Version: "1.2.3", Channel: "beta",
+			Artifacts: []artifactRecord{
+				{Link: fs.URL + "/download/myapp.deb", Platform: "linux", Arch: "amd64", Package: ".deb"},
+				{Link: fs.URL + "/download/myapp.exe", Platform: "windows", Arch: "amd64", Package: ".exe"},
+			},
+		},
+	}
+	fs.handle("/download/myapp.deb", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("deb-bytes"))
+	})
+	fs.handle("/download/myapp.exe", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(t.Context(), []string{"promote", "--app", "myapp", "--version", "1.2.3", "--from", "beta", "--to", "stable"})
+	if err == nil || !strings.Contains(err.Error(), "faynosync delete version --app myapp --version 1.2.3 --channel stable") {
+		t.Fatalf("expected partial promotion error, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Partially promoted myapp 1.2.3 to stable") || !strings.Contains(out.String(), "linux/amd64 .deb") {
+		t.Fatalf("expected promoted groups in output:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "windows/amd64") {
+		t.Fatalf("failed group must not be listed as promoted:\n%s", out.String())
+	}
+}
+
+func TestPromoteDryRunDoesNotUpload(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{
+			ID: "b1", AppName: "myapp", Version: "1.2.3", Channel: "beta",
+			Artifacts: []artifactRecord{{Link: "https://cdn/myapp.deb", Platform: "linux", Arch: "amd64", Package: ".deb"}},
+		},
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(t.Context(), []string{"promote", "--app", "myapp", "--version", "1.2.3", "--from", "beta", "--to", "stable", "--dry-run"})
+	if err != nil {
+		t.Fatalf("promote returned error: %v", err)
+	}
+
+	if got := fs.requestsTo("/upload"); len(got) != 0 {
+		t.Fatalf("expected no uploads in dry run, got %d", len(got))
+	}
+	if !strings.Contains(out.String(), "Dry run: would promote myapp 1.2.3 from beta to stable") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestPromoteRefusesExistingTargetVersion(t *testing.T) {
+	newFakeServer(t, []versionRecord{
+		{ID: "b1", AppName: "myapp", Version: "1.2.3", Channel: "beta", Artifacts: []artifactRecord{{Platform: "linux", Arch: "amd64"}}},
+		{ID: "s1", AppName: "myapp", Version: "1.2.3", Channel: "stable"},
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"promote", "--app", "myapp", "--version", "1.2.3", "--from", "beta", "--to", "stable"})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing version error, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"faynoSync-cli/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+// newProxyFunc picks the proxy for each request. An explicit http.proxy is
+// used for every host except those in http.no_proxy, or NO_PROXY when that
+// is not set. Without one, HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply as
+// usual, with http.no_proxy added on top.
+func newProxyFunc(settings config.HTTP) (proxyFunc, error) {
+	noProxy := strings.TrimSpace(settings.NoProxy)
+
+	value := strings.TrimSpace(settings.Proxy)
+	if value == "" {
+		if noProxy == "" {
+			return http.ProxyFromEnvironment, nil
+		}
+		return func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(noProxy, req.URL) {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		}, nil
+	}
+
+	proxyURL, err := url.Parse(value)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("http.proxy: invalid URL %q", value)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("http.proxy: unsupported scheme %q, use http, https, socks5 or socks5h", proxyURL.Scheme)
+	}
+	if noProxy == "" {
+		noProxy = envNoProxy()
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(noProxy, req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+func envNoProxy() string {
+	if value := os.Getenv("NO_PROXY"); value != "" {
+		return value
+	}
+	return os.Getenv("no_proxy")
+}
+
+// bypassProxy matches target against a NO_PROXY style list: "*", host names
+// that also match their subdomains (with or without a leading dot), IP
+// addresses, CIDR ranges, and any of those with a ":port" suffix.
+func bypassProxy(noProxy string, target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	port := target.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[target.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		domain := strings.TrimPrefix(entryHost, "*")
+		domain = strings.TrimPrefix(domain, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeProxy reports the proxy that requests to server will use, with
+// any password redacted.
+func describeProxy(proxy proxyFunc, server string) string {
+	req, err := http.NewRequest(http.MethodGet, server, nil)
+	if err != nil {
+		return "unknown"
+	}
+	proxyURL, err := proxy(req)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	if proxyURL == nil {
+		return "direct"
+	}
+	return proxyURL.Redacted()
+}
+
+func (a *App) logProxy(server string, settings config.HTTP) {
+	if !a.logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	proxy, err := newProxyFunc(settings)
+	if err != nil {
+		return
+	}
+
+	source := "environment"
+	switch {
+	case a.httpOverrides.Proxy != "":
+		source = "--proxy"
+	case strings.TrimSpace(settings.Proxy) != "":
+		source = "http.proxy"
+	}
+	a.logger.WithFields(map[string]any{
+		"server":   server,
+		"proxy":    describeProxy(proxy, server),
+		"source":   source,
+		"no_proxy": settings.NoProxy,
+	}).Debug("Effective proxy")
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"faynoSync-cli/internal/config"
+)
+
+func TestBypassProxy(t *testing.T) {
+	cases := []struct {
+		noProxy string
+		target  string
+		want    bool
+	}{
+		{"", "https://updates.example.com", false},
+		{"*", "https://updates.example.com", true},
+		{"example.com", "https://updates.example.com", true},
+		{".example.com", "https://example.com", true},
+		{"*.example.com", "https://updates.example.com", true},
+		{"example.com", "https://notexample.com", false},
+		{"example.com:443", "https://example.com", true},
+		{"example.com:8443", "https://example.com", false},
+		{"10.0.0.0/8", "http://10.1.2.3:8080", true},
+		{"10.0.0.0/8", "http://192.168.1.1", false},
+		{"other.org, 127.0.0.1", "http://127.0.0.1:9000", true},
+		{"::1", "http://[::1]:9000", true},
+	}
+	for _, tc := range cases {
+		target, err := url.Parse(tc.target)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tc.target, err)
+		}
+		if got := bypassProxy(tc.noProxy, target); got != tc.want {
+			t.Errorf("bypassProxy(%q, %s) = %v, want %v", tc.noProxy, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestHTTPProxyWithCredentials(t *testing.T) {
+	newFakeServer(t, nil)
+	target := httptest.NewServer(http.HandlerFunc(serveWhoami))
+	t.Cleanup(target.Close)
+	t.Setenv("FAYNOSYNC_URL", target.URL)
+
+	var hits atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpzZWNyZXQ=" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		serveWhoami(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"--log-level", "debug", "--proxy", proxyURL, "whoami"}); err != nil {
+		t.Fatalf("whoami through proxy returned error: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected one request through the proxy, got %d", hits.Load())
+	}
+	if !strings.Contains(out.String(), "Effective proxy") || strings.Contains(out.String(), "secret") {
+		t.Fatalf("expected a redacted proxy in debug output, got:\n%s", out.String())
+	}
+
+	if err := app.Run(t.Context(), []string{"--proxy", proxyURL, "--no-proxy", "127.0.0.1", "whoami"}); err != nil {
+		t.Fatalf("whoami with --no-proxy returned error: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected --no-proxy to bypass the proxy, got %d requests", hits.Load())
+	}
+
+	if _, err := config.Init("", config.Default()); err != nil {
+		t.Fatalf("init config: %v", err)
+	}
+	if err := app.Run(t.Context(), []string{"config", "set", "http.proxy", proxyURL}); err != nil {
+		t.Fatalf("config set http.proxy returned error: %v", err)
+	}
+	if err := app.Run(t.Context(), []string{"whoami"}); err != nil {
+		t.Fatalf("whoami with http.proxy returned error: %v", err)
+	}
+	if hits.Load() != 2 {
+		t.Fatalf("expected http.proxy to be used, got %d requests", hits.Load())
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	newFakeServer(t, nil)
+	target := httptest.NewServer(http.HandlerFunc(serveWhoami))
+	t.Cleanup(target.Close)
+	t.Setenv("FAYNOSYNC_URL", target.URL)
+
+	proxyAddr, hits := serveSOCKS5(t, "user", "secret")
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"--proxy", "socks5://user:wrong@" + proxyAddr, "whoami"}); err == nil {
+		t.Fatal("expected the SOCKS5 proxy to reject bad credentials")
+	}
+	if err := app.Run(t.Context(), []string{"--proxy", "socks5://user:secret@" + proxyAddr, "whoami"}); err != nil {
+		t.Fatalf("whoami through SOCKS5 returned error: %v", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("expected one tunnelled connection, got %d", hits.Load())
+	}
+}
+
+// serveSOCKS5 runs a minimal SOCKS5 server that requires username/password
+// authentication and supports CONNECT only.
+func serveSOCKS5(t *testing.T, user, password string) (string, *atomic.Int32) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	hits := &atomic.Int32{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				upstream, err := socks5Handshake(conn, user, password)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				hits.Add(1)
+				go func() { _, _ = io.Copy(upstream, conn) }()
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String(), hits
+}
+
+func socks5Handshake(conn net.Conn, user, password string) (net.Conn, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 2}); err != nil {
+		return nil, err
+	}
+
+	readField := func() (string, error) {
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", err
+		}
+		value := make([]byte, size[0])
+		_, err := io.ReadFull(conn, value)
+		return string(value), err
+	}
+	if _, err := io.ReadFull(conn, make([]byte, 1)); err != nil {
+		return nil, err
+	}
+	gotUser, err := readField()
+	if err != nil {
+		return nil, err
+	}
+	gotPassword, err := readField()
+	if err != nil {
+		return nil, err
+	}
+	if gotUser != user || gotPassword != password {
+		_, _ = conn.Write([]byte{1, 1})
+		return nil, io.EOF
+	}
+	if _, err := conn.Write([]byte{1, 0}); err != nil {
+		return nil, err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return nil, err
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return nil, err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		if host, err = readField(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, io.EOF
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return nil, err
+	}
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return nil, err
+	}
+	if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		upstream.Close()
+		return nil, err
+	}
+	return upstream, nil
+}
@@ -0,0 +1,345 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errPruneHelp = errors.New("prune help requested")
+
+const defaultPruneBatchSize = 10
+
+type pruneFlags struct {
+	targetFlags
+	KeepLast      int
+	OlderThan     time.Duration
+	KeepPublished bool
+	DryRun        bool
+	BatchSize     int
+	Yes           bool
+}
+
+type pruneDecision struct {
+	Record versionRecord
+	Delete bool
+	Reason string
+}
+
+func (a *App) runPrune(args []string) error {
+	flags, err := parsePruneFlags(args)
+	if err != nil {
+		if errors.Is(err, errPruneHelp) {
+			a.printPruneUsage()
+			return nil
+		}
+		return err
+	}
+	if err := flags.require("--app", "--channel"); err != nil {
+		return err
+	}
+	// Deleting a version removes the artifacts of every platform, so a
+	// platform or arch filter would delete more than it selected.
+	if flags.Platform != "" || flags.Arch != "" {
+		return errors.New("prune does not support --platform or --arch: deleting a version removes the artifacts of every platform")
+	}
+	if flags.KeepLast < 0 {
+		return errors.New("--keep-last must not be negative")
+	}
+	if flags.KeepLast == 0 && flags.OlderThan == 0 {
+		return errors.New("set --keep-last, --older-than or both")
+	}
+
+	client, err := a.newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	records, err := client.searchVersions(flags.query())
+	if err != nil {
+		return err
+	}
+
+	decisions := planPrune(records, flags, time.Now())
+	var candidates []versionRecord
+	for _, decision := range decisions {
+		if decision.Delete {
+			candidates = append(candidates, decision.Record)
+		}
+	}
+
+	_, _ = fmt.Fprintf(a.out, "%s on channel %s: %d versions, %d to delete\n", flags.AppName, flags.Channel, len(decisions), len(candidates))
+	for _, decision := range decisions {
+		action := "keep  "
+		if decision.Delete {
+			action = "delete"
+		}
+		_, _ = fmt.Fprintf(a.out, "  %s %-20s %s\n", action, decision.Record.Version, decision.Reason)
+	}
+
+	if flags.DryRun || len(candidates) == 0 {
+		return nil
+	}
+
+	if !flags.Yes {
+		ok, err := a.confirm(fmt.Sprintf("Delete %d versions?", len(candidates)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted: nothing was deleted")
+		}
+	}
+
+	batchSize := flags.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultPruneBatchSize
+	}
+
+	deleted := 0
+	var failures []string
+	batches := (len(candidates) + batchSize - 1) / batchSize
+	for batch := 0; batch < batches; batch++ {
+		start := batch * batchSize
+		end := min(start+batchSize, len(candidates))
+
+		batchDeleted := 0
+		for _, record := range candidates[start:end] {
+			if err := client.deleteVersion(record.ID); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", record.Version, err))
+				continue
+			}
+			batchDeleted++
+		}
+		deleted += batchDeleted
+
+		a.logger.WithFields(map[string]any{
+			"batch":   fmt.Sprintf("%d/%d", batch+1, batches),
+			"deleted": batchDeleted,
+			"failed":  end - start - batchDeleted,
+		}).Info("Prune batch finished")
+	}
+
+	_, _ = fmt.Fprintf(a.out, "Deleted %d of %d versions\n", deleted, len(candidates))
+	for _, failure := range failures {
+		_, _ = fmt.Fprintf(a.out, "  failed %s\n", failure)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d versions could not be deleted", len(failures))
+	}
+	return nil
+}
+
+func planPrune(records []versionRecord, flags pruneFlags, now time.Time) []pruneDecision {
+	sorted := append([]versionRecord(nil), records...)
+	sortVersionsDesc(sorted)
+
+	latestPublished := -1
+	for i, record := range sorted {
+		if record.Published {
+			latestPublished = i
+			break
+		}
+	}
+
+	decisions := make([]pruneDecision, 0, len(sorted))
+	for i, record := range sorted {
+		decision := pruneDecision{Record: record}
+		switch {
+		case i < flags.KeepLast:
+			decision.Reason = fmt.Sprintf("within last %d", flags.KeepLast)
+		case i == latestPublished:
+			decision.Reason = "latest published version"
+		case record.Intermediate:
+			decision.Reason = "intermediate version required for upgrade paths"
+		case flags.KeepPublished && record.Published:
+			decision.Reason = "published"
+		case flags.OlderThan > 0:
+			updated, err := parseRecordTime(record.UpdatedAt)
+			switch {
+			case err != nil:
+				decision.Reason = "unknown age"
+			case now.Sub(updated) < flags.OlderThan:
+				decision.Reason = "newer than " + formatAge(flags.OlderThan)
+			default:
+				decision.Delete = true
+				decision.Reason = "older than " + formatAge(flags.OlderThan)
+			}
+		default:
+			decision.Delete = true
+			decision.Reason = fmt.Sprintf("beyond last %d", flags.KeepLast)
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+func parseRecordTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST", "2006-01-02 15:04:05"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", value)
+}
+
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if num, ok := strings.CutSuffix(value, suffix); ok {
+			n, err := strconv.Atoi(num)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return parsed, nil
+}
+
+func formatAge(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func parsePruneFlags(args []string) (pruneFlags, error) {
+	var out pruneFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return pruneFlags{}, errPruneHelp
+		case arg == "--keep-last":
+			val, consumed, err := requireValue(args, i, "--keep-last")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			n, err := parseInt(val, "--keep-last")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.KeepLast = n
+			i += consumed
+		case strings.HasPrefix(arg, "--keep-last="):
+			n, err := parseInt(strings.TrimPrefix(arg, "--keep-last="), "--keep-last")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.KeepLast = n
+		case arg == "--older-than":
+			val, consumed, err := requireValue(args, i, "--older-than")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			d, err := parseAge(val)
+			if err != nil {
+				return pruneFlags{}, fmt.Errorf("--older-than: %w", err)
+			}
+			out.OlderThan = d
+			i += consumed
+		case strings.HasPrefix(arg, "--older-than="):
+			d, err := parseAge(strings.TrimPrefix(arg, "--older-than="))
+			if err != nil {
+				return pruneFlags{}, fmt.Errorf("--older-than: %w", err)
+			}
+			out.OlderThan = d
+		case arg == "--batch-size":
+			val, consumed, err := requireValue(args, i, "--batch-size")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			n, err := parseInt(val, "--batch-size")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.BatchSize = n
+			i += consumed
+		case strings.HasPrefix(arg, "--batch-size="):
+			n, err := parseInt(strings.TrimPrefix(arg, "--batch-size="), "--batch-size")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.BatchSize = n
+		case arg == "--keep-published":
+			val, consumed, err := parseBoolValue(args, i, "--keep-published")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.KeepPublished = val
+			i += consumed
+		case strings.HasPrefix(arg, "--keep-published="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--keep-published="), "--keep-published")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.KeepPublished = val
+		case arg == "--dry-run":
+			val, consumed, err := parseBoolValue(args, i, "--dry-run")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.DryRun = val
+			i += consumed
+		case strings.HasPrefix(arg, "--dry-run="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--dry-run="), "--dry-run")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.DryRun = val
+		case arg == "--yes" || arg == "-y":
+			val, consumed, err := parseBoolValue(args, i, "--yes")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.Yes = val
+			i += consumed
+		case strings.HasPrefix(arg, "--yes="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--yes="), "--yes")
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			out.Yes = val
+		default:
+			consumed, ok, err := parseTargetFlag(args, i, &out.targetFlags)
+			if err != nil {
+				return pruneFlags{}, err
+			}
+			if !ok {
+				return pruneFlags{}, fmt.Errorf("unknown prune flag: %s", arg)
+			}
+			i += consumed
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printPruneUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync prune
+
+Usage:
+  faynosync prune --app <name> --channel <value> [--keep-last <n>] [--older-than <age>] [flags]
+
+Deletes old versions from a channel. The latest published version and
+intermediate versions are always kept.
+
+Prune flags:
+  --app <name>
+  --channel <value>
+  --keep-last <n>        keep the n newest versions
+  --older-than <age>     only delete versions last updated before age (e.g. 30d, 2w, 12h)
+  --keep-published       never delete published versions
+  --batch-size <n>       versions deleted per batch (default: 10)
+  --dry-run              list candidates without deleting
+  --yes, -y              skip the confirmation prompt`)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanPruneHonoursKeepRules(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-60 * 24 * time.Hour).Format(time.RFC3339)
+	recent := now.Add(-2 * 24 * time.Hour).Format(time.RFC3339)
+
+	decisions := planPrune([]versionRecord{
+		{Version: "1.0.1", UpdatedAt: old},
+		{Version: "1.0.2", UpdatedAt: old, Intermediate: true},
+		{Version: "1.0.3", UpdatedAt: old, Published: true},
+		{Version: "1.0.4", UpdatedAt: old},
+		{Version: "1.0.5", UpdatedAt: recent},
+		{Version: "1.0.6", UpdatedAt: recent},
+	}, pruneFlags{KeepLast: 1, OlderThan: 30 * 24 * time.Hour}, now)
+
+	got := map[string]bool{}
+	for _, decision := range decisions {
+		got[decision.Record.Version] = decision.Delete
+	}
+	want := map[string]bool{
+		"1.0.6": false, // keep-last
+		"1.0.5": false, // too recent
+		"1.0.4": true,
+		"1.0.3": false, // latest published
+		"1.0.2": false, // intermediate
+		"1.0.1": true,
+	}
+	for version, del := range want {
+		if got[version] != del {
+			t.Fatalf("version %s: delete=%t, want %t", version, got[version], del)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := parseAge(in)
+		if err != nil || got != want {
+			t.Fatalf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseAge("soon"); err == nil {
+		t.Fatal("expected error for invalid age")
+	}
+}
+
+func TestPruneDeletesInBatches(t *testing.T) {
+	var records []versionRecord
+	for _, v := range []string{"1", "2", "3", "4", "5"} {
+		records = append(records, versionRecord{ID: "id" + v, AppName: "myapp", Version: "1.0." + v, Channel: "nightly"})
+	}
+	fs := newFakeServer(t, records)
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(t.Context(), []string{"prune", "--app", "myapp", "--channel", "nightly", "--keep-last", "2", "--batch-size", "2", "--yes"})
+	if err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+
+	deletes := fs.requestsTo("/apps/delete")
+	if len(deletes) != 3 {
+		t.Fatalf("expected three deletions, got %d", len(deletes))
+	}
+	if !strings.Contains(out.String(), "Deleted 3 of 3 versions") || !strings.Contains(out.String(), "batch=2/2") {
+		t.Fatalf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestPruneDryRunDoesNotDelete(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{ID: "a", AppName: "myapp", Version: "1.0.0", Channel: "nightly"},
+		{ID: "b", AppName: "myapp", Version: "1.0.1", Channel: "nightly"},
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"prune", "--app", "myapp", "--channel", "nightly", "--keep-last", "1", "--dry-run"}); err != nil {
+		t.Fatalf("prune returned error: %v", err)
+	}
+
+	if got := fs.requestsTo("/apps/delete"); len(got) != 0 {
+		t.Fatalf("expected no deletions in dry run, got %d", len(got))
+	}
+	if !strings.Contains(out.String(), "delete 1.0.0") {
+		t.Fatalf("expected candidate listing:\n%s", out.String())
+	}
+}
+
+func TestPruneRejectsPlatformFilter(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{ID: "a", AppName: "myapp", Version: "1.0.0", Channel: "nightly"},
+		{ID: "b", AppName: "myapp", Version: "1.0.1", Channel: "nightly"},
+	})
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"prune", "--app", "myapp", "--channel", "nightly", "--platform", "linux", "--keep-last", "1", "--yes"})
+	if err == nil || !strings.Contains(err.Error(), "--platform") {
+		t.Fatalf("expected --platform to be rejected, got %v", err)
+	}
+	if got := fs.requestsTo("/apps/delete"); len(got) != 0 {
+		t.Fatalf("expected no deletions, got %d", len(got))
+	}
+}
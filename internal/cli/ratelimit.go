@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"faynoSync-cli/internal/config"
+)
+
+const maxRateChunk = 32 << 10
+
+// rateLimiter is a token bucket shared by every reader it wraps, so parallel
+// transfers together stay under the configured rate. Tokens may go negative:
+// a caller reserves its bytes and sleeps until the bucket has refilled.
+type rateLimiter struct {
+	rate  int64
+	label string
+
+	mu     sync.Mutex
+	tokens float64
+	burst  float64
+	last   time.Time
+	// sent counts the bytes let through, for reporting the effective rate.
+	sent int64
+}
+
+func newRateLimiter(rate int64, label string) *rateLimiter {
+	burst := float64(min(max(rate/10, 1), maxRateChunk))
+	return &rateLimiter{
+		rate:   rate,
+		label:  label,
+		tokens: burst,
+		burst:  burst,
+		last:   time.Now(),
+	}
+}
+
+// wait reserves n bytes and blocks until the bucket has refilled, or until
+// ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.rate), l.burst)
+	l.last = now
+	l.tokens -= float64(n)
+	l.sent += int64(n)
+	delay := time.Duration(0)
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// total returns the number of bytes let through so far.
+func (l *rateLimiter) total() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.sent
+}
+
+// reader returns r throttled by l; a nil limiter leaves r as is. Waiting
+// stops with an error once ctx is done.
+func (l *rateLimiter) reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, limiter: l}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > int(r.limiter.burst) {
+		p = p[:int(r.limiter.burst)]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// formatRate renders bytes per second with the K, M and G suffixes that
+// http.limit_rate accepts.
+func formatRate(bytes int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "-"
+	}
+	rate := float64(bytes) / elapsed.Seconds()
+	for _, unit := range []string{"", "K", "M"} {
+		if rate < 1<<10 {
+			return fmt.Sprintf("%.1f%s/s", rate, unit)
+		}
+		rate /= 1 << 10
+	}
+	return fmt.Sprintf("%.1fG/s", rate)
+}
+
+// uploadLimiter returns the limiter for value. It is created once per App,
+// so every upload of one invocation shares the same budget.
+func (a *App) uploadLimiter(value string) (*rateLimiter, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	rate, err := config.ParseRate(value)
+	if err != nil {
+		return nil, fmt.Errorf("http.limit_rate: %w", err)
+	}
+	if a.limiter == nil || a.limiter.rate != rate {
+		a.limiter = newRateLimiter(rate, value)
+	}
+	return a.limiter, nil
+}
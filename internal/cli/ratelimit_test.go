@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterIsSharedAcrossReaders(t *testing.T) {
+	limiter := newRateLimiter(100<<10, "100K")
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = io.Copy(io.Discard, limiter.reader(t.Context(), bytes.NewReader(make([]byte, 20<<10))))
+		}()
+	}
+	wg.Wait()
+
+	// 60K at 100K/s, minus the 10K initial burst, takes about half a second.
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected parallel readers to share the rate, finished in %s", elapsed)
+	}
+}
+
+func TestRateLimiterStopsWhenContextIsDone(t *testing.T) {
+	limiter := newRateLimiter(1<<10, "1K")
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := io.Copy(io.Discard, limiter.reader(ctx, bytes.NewReader(make([]byte, 64<<10))))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the wait to stop with the context, took %s", elapsed)
+	}
+}
+
+func TestUploadWithLimitRate(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	var size int64
+	fs.handle("/upload", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+		}
+		size = r.MultipartForm.File["file"][0].Size
+		_, _ = w.Write([]byte(`{"uploaded_id":"1"}`))
+	})
+
+	artifact := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(artifact, make([]byte, 64<<10), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"--limit-rate", "1M", "upload", "--app", "myapp", "--file", artifact}); err != nil {
+		t.Fatalf("upload returned error: %v", err)
+	}
+	if size != 64<<10 {
+		t.Fatalf("expected the whole file to arrive, got %d bytes", size)
+	}
+	if !strings.Contains(out.String(), "limit_rate=1M/s") {
+		t.Fatalf("expected the rate in the upload output, got:\n%s", out.String())
+	}
+	if !regexp.MustCompile(` rate=[0-9.]+[KMG]?/s`).MatchString(out.String()) {
+		t.Fatalf("expected the effective rate in the upload output, got:\n%s", out.String())
+	}
+
+	err := app.Run(t.Context(), []string{"--limit-rate", "fast", "upload", "--app", "myapp", "--file", artifact})
+	if err == nil || !strings.Contains(err.Error(), "--limit-rate") {
+		t.Fatalf("expected invalid --limit-rate error, got %v", err)
+	}
+}
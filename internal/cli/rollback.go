@@ -0,0 +1,363 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"faynoSync-cli/internal/config"
+)
+
+var errRollbackHelp = errors.New("rollback help requested")
+
+type rollbackFlags struct {
+	targetFlags
+	Critical bool
+	DryRun   bool
+	Undo     string
+}
+
+type versionState struct {
+	Published bool `json:"published"`
+	Critical  bool `json:"critical"`
+}
+
+type rollbackChange struct {
+	ID           string       `json:"id"`
+	AppName      string       `json:"app_name"`
+	Version      string       `json:"version"`
+	Channel      string       `json:"channel"`
+	Intermediate bool         `json:"intermediate"`
+	Before       versionState `json:"before"`
+	After        versionState `json:"after"`
+}
+
+type rollbackRecord struct {
+	CreatedAt time.Time        `json:"created_at"`
+	Server    string           `json:"server"`
+	Changes   []rollbackChange `json:"changes"`
+}
+
+func (a *App) runRollback(args []string) error {
+	flags, err := parseRollbackFlags(args)
+	if err != nil {
+		if errors.Is(err, errRollbackHelp) {
+			a.printRollbackUsage()
+			return nil
+		}
+		return err
+	}
+
+	client, err := a.newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(flags.Undo) != "" {
+		return a.undoRollback(client, flags)
+	}
+
+	if err := flags.require("--app", "--channel"); err != nil {
+		return err
+	}
+	if flags.Version != "" {
+		return errors.New("rollback always targets the latest published version, --version is not supported")
+	}
+
+	records, err := client.searchVersions(flags.query())
+	if err != nil {
+		return err
+	}
+
+	changes, err := planRollback(records, flags.Critical)
+	if err != nil {
+		return fmt.Errorf("%s on channel %q: %w", flags.AppName, flags.Channel, err)
+	}
+
+	if flags.DryRun {
+		_, _ = fmt.Fprintf(a.out, "Dry run: would roll back %s on channel %s\n", flags.AppName, flags.Channel)
+		writeRollbackChanges(a.out, changes, false)
+		return nil
+	}
+
+	record := rollbackRecord{CreatedAt: time.Now().UTC(), Server: client.server}
+	applyErr := a.applyVersionStates(client, changes, false, &record.Changes)
+	if len(record.Changes) == 0 {
+		return applyErr
+	}
+
+	undoPath, saveErr := saveRollbackRecord(flags.AppName, flags.Channel, record)
+
+	_, _ = fmt.Fprintf(a.out, "Rolled back %s on channel %s\n", flags.AppName, flags.Channel)
+	writeRollbackChanges(a.out, record.Changes, false)
+	if saveErr != nil {
+		a.logger.WithError(saveErr).Warn("Could not save undo record")
+	} else {
+		_, _ = fmt.Fprintf(a.out, "Undo record: %s\n", undoPath)
+	}
+
+	return applyErr
+}
+
+func (a *App) undoRollback(client *apiClient, flags rollbackFlags) error {
+	raw, err := os.ReadFile(strings.TrimSpace(flags.Undo))
+	if err != nil {
+		return err
+	}
+
+	var record rollbackRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return fmt.Errorf("parse undo record: %w", err)
+	}
+	if len(record.Changes) == 0 {
+		return errors.New("undo record contains no changes")
+	}
+	if record.Server != client.server {
+		return fmt.Errorf("the undo record was written for %s, but the profile uses %s", record.Server, client.server)
+	}
+
+	reversed := make([]rollbackChange, 0, len(record.Changes))
+	for i := len(record.Changes) - 1; i >= 0; i-- {
+		reversed = append(reversed, record.Changes[i])
+	}
+
+	if flags.DryRun {
+		_, _ = fmt.Fprintln(a.out, "Dry run: would restore")
+		writeRollbackChanges(a.out, reversed, true)
+		return nil
+	}
+
+	var applied []rollbackChange
+	err = a.applyVersionStates(client, reversed, true, &applied)
+	if len(applied) > 0 {
+		_, _ = fmt.Fprintln(a.out, "Restored")
+		writeRollbackChanges(a.out, applied, true)
+	}
+	return err
+}
+
+func planRollback(records []versionRecord, markCritical bool) ([]rollbackChange, error) {
+	sorted := append([]versionRecord(nil), records...)
+	sortVersionsDesc(sorted)
+
+	latest := -1
+	for i, record := range sorted {
+		if record.Published {
+			latest = i
+			break
+		}
+	}
+	if latest < 0 {
+		return nil, errors.New("no published version to roll back")
+	}
+	if latest+1 >= len(sorted) {
+		return nil, fmt.Errorf("no version older than %s to roll back to", sorted[latest].Version)
+	}
+
+	bad := sorted[latest]
+	previous := sorted[latest+1]
+
+	changes := []rollbackChange{
+		newRollbackChange(bad, versionState{Published: false, Critical: bad.Critical}),
+	}
+	restored := versionState{Published: true, Critical: previous.Critical || markCritical}
+	if restored != (versionState{Published: previous.Published, Critical: previous.Critical}) {
+		changes = append(changes, newRollbackChange(previous, restored))
+	}
+	return changes, nil
+}
+
+func newRollbackChange(record versionRecord, after versionState) rollbackChange {
+	return rollbackChange{
+		ID:           record.ID,
+		AppName:      record.AppName,
+		Version:      record.Version,
+		Channel:      record.Channel,
+		Intermediate: record.Intermediate,
+		Before:       versionState{Published: record.Published, Critical: record.Critical},
+		After:        after,
+	}
+}
+
+func (a *App) applyVersionStates(client *apiClient, changes []rollbackChange, restore bool, applied *[]rollbackChange) error {
+	for _, change := range changes {
+		state := change.After
+		if restore {
+			state = change.Before
+		}
+
+		err := client.updateVersion(updateData{
+			ID:           change.ID,
+			AppName:      change.AppName,
+			Version:      change.Version,
+			Channel:      change.Channel,
+			Publish:      state.Published,
+			Critical:     state.Critical,
+			Intermediate: change.Intermediate,
+		})
+		if err != nil {
+			return fmt.Errorf("update %s %s: %w", change.AppName, change.Version, err)
+		}
+
+		a.logger.WithFields(map[string]any{
+			"version":   change.Version,
+			"published": state.Published,
+			"critical":  state.Critical,
+		}).Debug("Version updated")
+		*applied = append(*applied, change)
+	}
+	return nil
+}
+
+func writeRollbackChanges(w io.Writer, changes []rollbackChange, restore bool) {
+	for _, change := range changes {
+		from, to := change.Before, change.After
+		if restore {
+			from, to = to, from
+		}
+
+		var diffs []string
+		if from.Published != to.Published {
+			diffs = append(diffs, fmt.Sprintf("published %t -> %t", from.Published, to.Published))
+		}
+		if from.Critical != to.Critical {
+			diffs = append(diffs, fmt.Sprintf("critical %t -> %t", from.Critical, to.Critical))
+		}
+		if len(diffs) == 0 {
+			diffs = append(diffs, "unchanged")
+		}
+		_, _ = fmt.Fprintf(w, "  %s (%s): %s\n", change.Version, change.Channel, strings.Join(diffs, ", "))
+	}
+}
+
+// recordDir returns the directory for records of the given kind in the
+// per-user config directory. It is kept private to the user, since records
+// name apps, versions and servers.
+func recordDir(kind string) (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, kind)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return dir, os.Chmod(dir, 0o700)
+}
+
+func saveRollbackRecord(app, channel string, record rollbackRecord) (string, error) {
+	dir, err := recordDir("rollbacks")
+	if err != nil {
+		return "", err
+	}
+
+	out, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.json", safeFileName(app), safeFileName(channel), record.CreatedAt.Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func safeFileName(in string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, in)
+}
+
+func parseRollbackFlags(args []string) (rollbackFlags, error) {
+	var out rollbackFlags
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimSpace(args[i])
+		switch {
+		case arg == "-h" || arg == "--help" || arg == "help":
+			return rollbackFlags{}, errRollbackHelp
+		case arg == "--critical":
+			val, consumed, err := parseBoolValue(args, i, "--critical")
+			if err != nil {
+				return rollbackFlags{}, err
+			}
+			out.Critical = val
+			i += consumed
+		case strings.HasPrefix(arg, "--critical="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--critical="), "--critical")
+			if err != nil {
+				return rollbackFlags{}, err
+			}
+			out.Critical = val
+		case arg == "--dry-run":
+			val, consumed, err := parseBoolValue(args, i, "--dry-run")
+			if err != nil {
+				return rollbackFlags{}, err
+			}
+			out.DryRun = val
+			i += consumed
+		case strings.HasPrefix(arg, "--dry-run="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--dry-run="), "--dry-run")
+			if err != nil {
+				return rollbackFlags{}, err
+			}
+			out.DryRun = val
+		case arg == "--undo":
+			val, consumed, err := requireValue(args, i, "--undo")
+			if err != nil {
+				return rollbackFlags{}, err
+			}
+			out.Undo = val
+			i += consumed
+		case strings.HasPrefix(arg, "--undo="):
+			out.Undo = strings.TrimPrefix(arg, "--undo=")
+		default:
+			consumed, ok, err := parseTargetFlag(args, i, &out.targetFlags)
+			if err != nil {
+				return rollbackFlags{}, err
+			}
+			if !ok {
+				return rollbackFlags{}, fmt.Errorf("unknown rollback flag: %s", arg)
+			}
+			i += consumed
+		}
+	}
+
+	return out, nil
+}
+
+func (a *App) printRollbackUsage() {
+	dir, err := config.Dir()
+	if err != nil {
+		dir = "the config directory"
+	}
+	_, _ = fmt.Fprintf(a.out, `faynosync rollback
+
+Usage:
+  faynosync rollback --app <name> --channel <value> [flags]
+  faynosync rollback --undo <record>
+
+Unpublishes the latest published version and publishes the version before it.
+An undo record is written to %s after every rollback.
+
+Rollback flags:
+  --app <name>
+  --channel <value>
+  --platform <value>
+  --arch <value>
+  --critical             mark the restored version critical to force the downgrade
+  --dry-run              print what would change without updating anything
+  --undo <record>        restore the state saved in an undo record
+`, filepath.Join(dir, "rollbacks"))
+}
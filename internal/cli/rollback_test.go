@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.10", "1.2.9", 1},
+		{"0.0.0.1", "0.0.0.2", -1},
+		{"v1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.0-rc1", 1},
+		{"1.0.0.1", "1.0.0", 1},
+	}
+	for _, tc := range cases {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Fatalf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestPlanRollbackRestoresPreviousVersion(t *testing.T) {
+	changes, err := planRollback([]versionRecord{
+		{ID: "a", Version: "1.9.0", Published: true},
+		{ID: "b", Version: "1.10.0", Published: true},
+		{ID: "c", Version: "1.11.0"},
+	}, true)
+	if err != nil {
+		t.Fatalf("planRollback returned error: %v", err)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("expected two changes, got %+v", changes)
+	}
+	if changes[0].Version != "1.10.0" || changes[0].After.Published {
+		t.Fatalf("expected 1.10.0 to be unpublished, got %+v", changes[0])
+	}
+	if changes[1].Version != "1.9.0" || !changes[1].After.Published || !changes[1].After.Critical {
+		t.Fatalf("expected 1.9.0 to be published and critical, got %+v", changes[1])
+	}
+}
+
+func TestRollbackWritesUndoRecordThatRestoresState(t *testing.T) {
+	fs := newFakeServer(t, []versionRecord{
+		{ID: "a", AppName: "myapp", Version: "1.0.0", Channel: "stable"},
+		{ID: "b", AppName: "myapp", Version: "1.1.0", Channel: "stable", Published: true},
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"rollback", "--app", "myapp", "--channel", "stable"}); err != nil {
+		t.Fatalf("rollback returned error: %v", err)
+	}
+
+	updates := fs.requestsTo("/apps/update")
+	if len(updates) != 2 {
+		t.Fatalf("expected two updates, got %d", len(updates))
+	}
+	if !strings.Contains(updates[0].Body, `"id":"b"`) || !strings.Contains(updates[0].Body, `"publish":false`) {
+		t.Fatalf("expected 1.1.0 to be unpublished first:\n%s", updates[0].Body)
+	}
+	if !strings.Contains(out.String(), "1.1.0 (stable): published true -> false") {
+		t.Fatalf("expected change summary in output:\n%s", out.String())
+	}
+
+	match := regexp.MustCompile(`Undo record: (\S+)`).FindStringSubmatch(out.String())
+	if match == nil {
+		t.Fatalf("expected undo record path in output:\n%s", out.String())
+	}
+	if runtime.GOOS != "windows" {
+		for path, want := range map[string]os.FileMode{match[1]: 0o600, filepath.Dir(match[1]): 0o700} {
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != want {
+				t.Fatalf("expected %s to have mode %o, got %v, %v", path, want, info, err)
+			}
+		}
+	}
+
+	t.Setenv("FAYNOSYNC_URL", "https://elsewhere.example.com")
+	if err := app.Run(t.Context(), []string{"rollback", "--undo", match[1]}); err == nil || !strings.Contains(err.Error(), "written for "+fs.URL) {
+		t.Fatalf("expected undo on another server to be refused, got %v", err)
+	}
+	t.Setenv("FAYNOSYNC_URL", fs.URL)
+
+	out.Reset()
+	if err := app.Run(t.Context(), []string{"rollback", "--undo", match[1]}); err != nil {
+		t.Fatalf("rollback --undo returned error: %v", err)
+	}
+
+	updates = fs.requestsTo("/apps/update")
+	if len(updates) != 4 {
+		t.Fatalf("expected two more updates, got %d", len(updates))
+	}
+	if !strings.Contains(updates[3].Body, `"id":"b"`) || !strings.Contains(updates[3].Body, `"publish":true`) {
+		t.Fatalf("expected 1.1.0 to be republished last:\n%s", updates[3].Body)
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// interruptError is the context cause after SIGINT or SIGTERM.
+type interruptError struct {
+	signal os.Signal
+}
+
+func (e *interruptError) Error() string {
+	return "interrupted by " + signalName(e.signal)
+}
+
+func signalName(sig os.Signal) string {
+	switch sig {
+	case os.Interrupt:
+		return "SIGINT"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	default:
+		return sig.String()
+	}
+}
+
+// SignalContext returns a context that is cancelled on the first SIGINT or
+// SIGTERM, so in-flight requests stop and commands can clean up. A second
+// signal exits immediately. The returned stop function releases the handler.
+func SignalContext(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(&interruptError{signal: sig})
+		case <-done:
+			return
+		}
+		select {
+		case sig := <-signals:
+			fmt.Fprintln(os.Stderr, "Error:", &interruptError{signal: sig})
+			os.Exit(ExitCode(&interruptError{signal: sig}))
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			cancel(nil)
+		})
+	}
+}
+
+// ExitCode maps an error returned by Run to a process exit code: 128 plus
+// the signal number after an interrupt, as shells do, and 1 otherwise.
+func ExitCode(err error) int {
+	var interrupted *interruptError
+	if errors.As(err, &interrupted) {
+		if sig, ok := interrupted.signal.(syscall.Signal); ok {
+			return 128 + int(sig)
+		}
+		return 130
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
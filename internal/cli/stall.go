@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// stallError is returned when a transfer makes no progress for the stall
+// timeout.
+type stallError struct {
+	timeout time.Duration
+}
+
+func (e *stallError) Error() string {
+	return fmt.Sprintf("transfer stalled: no data moved for %s", e.timeout)
+}
+
+// stallTransport aborts a request when its body, or the response body, stops
+// moving. The watchdog only runs while bytes are expected to flow: while the
+// request body is being read and while the response body is being read.
+// Dialing, the TLS handshake and the wait for response headers have their
+// own transport timeouts.
+type stallTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *stallTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	watchdog := &stallWatchdog{timeout: t.timeout, cancel: cancel}
+	// The timer is armed once bytes are expected to move.
+	watchdog.timer = time.AfterFunc(math.MaxInt64, watchdog.fire)
+
+	req = req.WithContext(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &stallBody{ReadCloser: req.Body, watchdog: watchdog, ctx: ctx, request: true}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		watchdog.stop()
+		err = stallCause(ctx, err)
+		cancel(nil)
+		return nil, err
+	}
+
+	watchdog.start()
+	resp.Body = &stallBody{ReadCloser: resp.Body, watchdog: watchdog, ctx: ctx, cancel: cancel}
+	return resp, nil
+}
+
+type stallWatchdog struct {
+	timeout time.Duration
+	cancel  context.CancelCauseFunc
+
+	mu    sync.Mutex
+	timer *time.Timer
+	done  bool
+}
+
+func (w *stallWatchdog) fire() {
+	w.cancel(&stallError{timeout: w.timeout})
+}
+
+// start (re)arms the watchdog after progress.
+func (w *stallWatchdog) start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *stallWatchdog) pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer.Stop()
+}
+
+func (w *stallWatchdog) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	w.timer.Stop()
+}
+
+type stallBody struct {
+	io.ReadCloser
+	watchdog *stallWatchdog
+	ctx      context.Context
+	// request marks the request body: reaching its end pauses the watchdog
+	// until response headers arrive.
+	request bool
+	cancel  context.CancelCauseFunc
+}
+
+func (b *stallBody) Read(p []byte) (int, error) {
+	b.watchdog.start()
+	n, err := b.ReadCloser.Read(p)
+	switch {
+	case err == nil:
+		b.watchdog.start()
+	case err == io.EOF && b.request:
+		b.watchdog.pause()
+	case err == io.EOF:
+		b.watchdog.stop()
+	default:
+		err = stallCause(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *stallBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.request {
+		b.watchdog.stop()
+		b.cancel(nil)
+	}
+	return err
+}
+
+// stallCause replaces the generic cancellation error with the stall error
+// when the watchdog cancelled the request.
+func stallCause(ctx context.Context, err error) error {
+	var stalled *stallError
+	if cause := context.Cause(ctx); errors.As(cause, &stalled) {
+		return fmt.Errorf("%w (%v)", stalled, err)
+	}
+	return err
+}
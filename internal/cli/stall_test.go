@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStallTimeoutAbortsStuckResponse(t *testing.T) {
+	newFakeServer(t, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"username":`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FAYNOSYNC_URL", srv.URL)
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	start := time.Now()
+	err := app.Run(t.Context(), []string{"--stall-timeout", "200ms", "whoami"})
+	if err == nil || !strings.Contains(err.Error(), "transfer stalled") {
+		t.Fatalf("expected a stall error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("stall detection took %s", elapsed)
+	}
+}
+
+func TestStallTimeoutAbortsStuckUpload(t *testing.T) {
+	newFakeServer(t, nil)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never read the body, so the client blocks once socket buffers fill.
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	t.Setenv("FAYNOSYNC_URL", srv.URL)
+
+	artifact := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(artifact, make([]byte, 64<<20), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"--stall-timeout", "300ms", "upload", "--app", "myapp", "--file", artifact})
+	if err == nil || !strings.Contains(err.Error(), "transfer stalled") {
+		t.Fatalf("expected a stall error, got %v", err)
+	}
+}
+
+func TestResponseTimeoutIsSeparateFromStallTimeout(t *testing.T) {
+	newFakeServer(t, nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(400 * time.Millisecond)
+		serveWhoami(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("FAYNOSYNC_URL", srv.URL)
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"--stall-timeout", "100ms", "whoami"}); err != nil {
+		t.Fatalf("a slow server should not count as a stalled transfer: %v", err)
+	}
+
+	err := app.Run(t.Context(), []string{"--response-timeout", "100ms", "whoami"})
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Fatalf("expected a response header timeout, got %v", err)
+	}
+
+	err = app.Run(t.Context(), []string{"--timeout", "100ms", "whoami"})
+	if err == nil || !strings.Contains(err.Error(), "Client.Timeout") {
+		t.Fatalf("expected the overall timeout, got %v", err)
+	}
+
+	if err := app.Run(t.Context(), []string{"--stall-timeout", "soon", "whoami"}); err == nil || !strings.Contains(err.Error(), "--stall-timeout") {
+		t.Fatalf("expected an invalid --stall-timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   string
+}
+
+type fakeServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	records  []versionRecord
+	requests []recordedRequest
+	handlers map[string]http.HandlerFunc
+}
+
+func newFakeServer(t *testing.T, records []versionRecord) *fakeServer {
+	t.Helper()
+
+	fs := &fakeServer{
+		records:  records,
+		handlers: map[string]http.HandlerFunc{},
+	}
+	fs.Server = httptest.NewServer(http.HandlerFunc(fs.serve))
+	t.Cleanup(fs.Close)
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("FAYNOSYNC_CONFIG", "")
+	t.Setenv("FAYNOSYNC_TOKEN", "test-token")
+	t.Setenv("FAYNOSYNC_URL", fs.URL)
+	t.Setenv("FAYNOSYNC_ACCOUNT", "tester")
+	return fs
+}
+
+func (fs *fakeServer) handle(path string, handler http.HandlerFunc) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.handlers[path] = handler
+}
+
+func (fs *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	fs.mu.Lock()
+	fs.requests = append(fs.requests, recordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Body:   string(body),
+	})
+	handler := fs.handlers[r.URL.Path]
+	fs.mu.Unlock()
+
+	if handler != nil {
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		handler(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/search":
+		fs.serveSearch(w, r)
+	default:
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true})
+	}
+}
+
+func (fs *fakeServer) serveSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fs.mu.Lock()
+	var items []versionRecord
+	for _, record := range fs.records {
+		if app := q.Get("app_name"); app != "" && record.AppName != app {
+			continue
+		}
+		if channel := q.Get("channel"); channel != "" && record.Channel != channel {
+			continue
+		}
+		items = append(items, record)
+	}
+	fs.mu.Unlock()
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"items": items, "total": len(items)})
+}
+
+func (fs *fakeServer) requestsTo(path string) []recordedRequest {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var out []recordedRequest
+	for _, req := range fs.requests {
+		if req.Path == path {
+			out = append(out, req)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveWhoami(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(`{"username":"ci","is_admin":true}`))
+}
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+// newClientCert returns a self-signed client certificate and key as PEM
+// files, plus a pool that trusts it.
+func newClientCert(t *testing.T) (string, string, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "faynosync-ci"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return writePEM(t, "client.pem", "CERTIFICATE", der), writePEM(t, "client-key.pem", "EC PRIVATE KEY", keyDER), pool
+}
+
+func TestCABundleAndInsecure(t *testing.T) {
+	newFakeServer(t, nil)
+	srv := httptest.NewTLSServer(http.HandlerFunc(serveWhoami))
+	t.Cleanup(srv.Close)
+	t.Setenv("FAYNOSYNC_URL", srv.URL)
+	bundle := writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"whoami"}); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected certificate error without a CA bundle, got %v", err)
+	}
+
+	if err := app.Run(t.Context(), []string{"--ca-bundle", bundle, "whoami"}); err != nil {
+		t.Fatalf("whoami with --ca-bundle returned error: %v", err)
+	}
+
+	out := bytes.NewBuffer(nil)
+	app = New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"--insecure", "whoami"}); err != nil {
+		t.Fatalf("whoami with --insecure returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "TLS certificate verification is disabled") {
+		t.Fatalf("expected --insecure warning, got:\n%s", out.String())
+	}
+}
+
+func TestClientCertificateAndMinTLS(t *testing.T) {
+	newFakeServer(t, nil)
+	certPath, keyPath, clientCAs := newClientCert(t)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(serveWhoami))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MaxVersion: tls.VersionTLS12,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	t.Setenv("FAYNOSYNC_URL", srv.URL)
+	bundle := writePEM(t, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"--ca-bundle", bundle, "whoami"}); err == nil {
+		t.Fatal("expected the gateway to reject a request without a client certificate")
+	}
+
+	if err := app.Run(t.Context(), []string{"--ca-bundle", bundle, "--client-cert", certPath, "--client-key", keyPath, "whoami"}); err != nil {
+		t.Fatalf("whoami with client certificate returned error: %v", err)
+	}
+
+	err := app.Run(t.Context(), []string{"--ca-bundle", bundle, "--client-cert", certPath, "--client-key", keyPath, "--tls-min-version", "1.3", "whoami"})
+	if err == nil {
+		t.Fatal("expected a TLS 1.2 server to be refused with --tls-min-version 1.3")
+	}
+}
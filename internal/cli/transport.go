@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"faynoSync-cli/internal/config"
+)
+
+// retryBackoff is the wait before the first retry; it doubles per attempt.
+var retryBackoff = time.Second
+
+// httpSettings applies the global TLS, proxy, rate and timeout flags on top
+// of the configured settings. Every client the App creates goes through it.
+func (a *App) httpSettings(settings config.HTTP) config.HTTP {
+	flags := a.httpOverrides
+	if flags.CABundle != "" {
+		settings.CABundle = flags.CABundle
+	}
+	if flags.ClientCert != "" {
+		settings.ClientCert = flags.ClientCert
+		settings.ClientKey = flags.ClientKey
+	} else if flags.ClientKey != "" {
+		settings.ClientKey = flags.ClientKey
+	}
+	if flags.MinTLS != "" {
+		settings.MinTLS = flags.MinTLS
+	}
+	if flags.Insecure {
+		settings.Insecure = true
+	}
+	if flags.Proxy != "" {
+		settings.Proxy = flags.Proxy
+	}
+	if flags.NoProxy != "" {
+		settings.NoProxy = flags.NoProxy
+	}
+	if flags.LimitRate != "" {
+		settings.LimitRate = flags.LimitRate
+	}
+	for _, field := range []struct {
+		dst *string
+		val string
+	}{
+		{&settings.Timeout, flags.Timeout},
+		{&settings.ConnectTimeout, flags.ConnectTimeout},
+		{&settings.TLSTimeout, flags.TLSTimeout},
+		{&settings.ResponseTimeout, flags.ResponseTimeout},
+		{&settings.StallTimeout, flags.StallTimeout},
+	} {
+		if field.val != "" {
+			*field.dst = field.val
+		}
+	}
+
+	if settings.Insecure {
+		a.logger.Warn("TLS certificate verification is disabled")
+	}
+	return settings
+}
+
+// newHTTPClient builds a client with a deadline per phase: dialing, the TLS
+// handshake, and the wait for response headers once the request is sent.
+// Transfers are aborted when no bytes move for http.stall_timeout, and
+// http.timeout, when set, caps the whole request.
+func newHTTPClient(settings config.HTTP) (*http.Client, error) {
+	var timeouts [5]time.Duration
+	for i, setting := range []struct {
+		key      string
+		value    string
+		fallback time.Duration
+	}{
+		{"http.timeout", settings.Timeout, 0},
+		{"http.connect_timeout", settings.ConnectTimeout, config.DefaultConnectTimeout},
+		{"http.tls_timeout", settings.TLSTimeout, config.DefaultTLSTimeout},
+		{"http.response_timeout", settings.ResponseTimeout, config.DefaultResponseTimeout},
+		{"http.stall_timeout", settings.StallTimeout, config.DefaultStallTimeout},
+	} {
+		timeouts[i] = setting.fallback
+		if value := strings.TrimSpace(setting.value); value != "" {
+			d, err := config.ParseTimeout(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", setting.key, err)
+			}
+			timeouts[i] = d
+		}
+	}
+	overall, connect, handshake, response, stall := timeouts[0], timeouts[1], timeouts[2], timeouts[3], timeouts[4]
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = handshake
+	transport.ResponseHeaderTimeout = response
+	proxy, err := newProxyFunc(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+	tlsConfig, err := newTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout:   overall,
+		Transport: &stallTransport{base: transport, timeout: stall},
+	}, nil
+}
+
+func newTLSConfig(settings config.HTTP) (*tls.Config, error) {
+	out := &tls.Config{}
+
+	if path := strings.TrimSpace(settings.CABundle); path != "" {
+		pool, err := loadCABundle(path)
+		if err != nil {
+			return nil, err
+		}
+		out.RootCAs = pool
+	}
+
+	if certPath := strings.TrimSpace(settings.ClientCert); certPath != "" {
+		// A single PEM file may hold both the certificate and the key.
+		keyPath := strings.TrimSpace(settings.ClientKey)
+		if keyPath == "" {
+			keyPath = certPath
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("http.client_cert: %w", err)
+		}
+		out.Certificates = []tls.Certificate{cert}
+	} else if strings.TrimSpace(settings.ClientKey) != "" {
+		return nil, errors.New("http.client_key: set http.client_cert as well")
+	}
+
+	if value := strings.TrimSpace(settings.MinTLS); value != "" {
+		version, ok := config.TLSVersions[value]
+		if !ok {
+			return nil, fmt.Errorf("http.min_tls: unsupported TLS version %q, use 1.2 or 1.3", value)
+		}
+		out.MinVersion = version
+	}
+
+	out.InsecureSkipVerify = settings.Insecure
+	return out, nil
+}
+
+// loadCABundle adds the certificates in path to the system roots.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("http.ca_bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, fmt.Errorf("http.ca_bundle: no PEM certificates in %s", path)
+	}
+	return pool, nil
+}
+
+// retryable reports whether a failed request may be sent again. Only
+// idempotent methods are retried, on network errors and gateway errors.
+func retryable(method string, err error) bool {
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	switch apiErr.Status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,6 +32,11 @@ type uploadFlags struct {
 	Changelog      string
 	ChangelogFile  string
 	ChangelogStdin bool
+	Preflight      bool
+	Resume         string
+	// ContentLength is nil when neither the flag nor its env var was given,
+	// so upload.content_length can supply the default.
+	ContentLength *bool
 }
 
 type uploadData struct {
@@ -55,85 +61,243 @@ func (a *App) runUpload(args []string) error {
 		return err
 	}
 
+	var state uploadState
+	if flags.Resume != "" {
+		if state, err = loadUploadState(flags.Resume); err != nil {
+			return err
+		}
+		if err := state.checkFiles(); err != nil {
+			return err
+		}
+		flags.Files = state.paths()
+		flags.AppName = state.Payload.AppName
+	}
+
+	if err := a.applyUploadDefaults(&flags); err != nil {
+		return err
+	}
+
 	if len(flags.Files) == 0 {
 		return errors.New("at least one --file is required")
 	}
 
-	runtimeCfg, _, err := config.LoadRuntime()
+	client, err := a.newAPIClient()
 	if err != nil {
 		return err
 	}
 
-	endpoint := strings.TrimRight(runtimeCfg.Server, "/") + "/upload"
-	changelog, err := a.resolveChangelog(flags)
-	if err != nil {
-		return err
+	if flags.Preflight {
+		if err := a.preflightUpload(client, flags.AppName); err != nil {
+			return err
+		}
 	}
 
-	payload := uploadData{
-		AppName:      flags.AppName,
-		Version:      flags.Version,
-		Channel:      flags.Channel,
-		Publish:      flags.Publish,
-		Critical:     flags.Critical,
-		Intermediate: flags.Intermediate,
-		Platform:     flags.Platform,
-		Arch:         flags.Arch,
-		Changelog:    changelog,
+	var payload uploadData
+	if flags.Resume != "" {
+		payload = state.Payload
+		if err := checkResume(client, state); err != nil {
+			return err
+		}
+	} else {
+		changelog, err := a.resolveChangelog(flags)
+		if err != nil {
+			return err
+		}
+
+		payload = uploadData{
+			AppName:      flags.AppName,
+			Version:      flags.Version,
+			Channel:      flags.Channel,
+			Publish:      flags.Publish,
+			Critical:     flags.Critical,
+			Intermediate: flags.Intermediate,
+			Platform:     flags.Platform,
+			Arch:         flags.Arch,
+			Changelog:    changelog,
+		}
 	}
 
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return err
+	client.contentLength = *flags.ContentLength
+	if client.limiter != nil {
+		a.logger.WithFields(map[string]any{
+			"files":      len(flags.Files),
+			"limit_rate": client.limiter.label + "/s",
+		}).Info("Uploading with bandwidth limit")
 	}
 
-	bodyReader, contentType := buildUploadBody(flags.Files, string(payloadJSON))
-	req, err := http.NewRequest(http.MethodPost, endpoint, bodyReader)
+	var sentBefore int64
+	if client.limiter != nil {
+		sentBefore = client.limiter.total()
+	}
+	started := time.Now()
+	respBody, err := client.upload(fileParts(flags.Files), payload)
+	if err != nil && a.ctx.Err() != nil {
+		return a.interruptedUpload(client, flags.Files, payload, flags.Resume)
+	}
 	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) {
+			a.logger.WithFields(map[string]any{
+				"status": apiErr.Status,
+				"body":   apiErr.Body,
+			}).Error("upload failed")
+			return nil
+		}
 		return err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+runtimeCfg.Token)
-	req.Header.Set("Content-Type", contentType)
+	fields := map[string]any{
+		"files":       len(flags.Files),
+		"app":         payload.AppName,
+		"version":     payload.Version,
+		"uploaded_id": extractUploadedID(respBody),
+	}
+	if client.limiter != nil {
+		fields["limit_rate"] = client.limiter.label + "/s"
+		fields["rate"] = formatRate(client.limiter.total()-sentBefore, time.Since(started))
+	}
+	a.logger.WithFields(fields).Info("Upload completed")
 
-	client := &http.Client{Timeout: 5 * time.Minute}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if flags.Resume != "" {
+		if err := os.Remove(flags.Resume); err != nil {
+			a.logger.WithError(err).Warn("Could not remove upload state")
+		}
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+// applyUploadDefaults fills flags left empty on the command line from the
+// upload section of the resolved config. File patterns from a project config
+// are relative to the directory holding .faynosync.yaml.
+func (a *App) applyUploadDefaults(flags *uploadFlags) error {
+	settings, err := config.Resolve(a.configPath, a.profile)
 	if err != nil {
 		return err
 	}
+	defaults := settings.Values.Upload
+
+	for _, field := range []struct {
+		dst *string
+		val string
+	}{
+		{&flags.AppName, defaults.App},
+		{&flags.Channel, defaults.Channel},
+		{&flags.Platform, defaults.Platform},
+		{&flags.Arch, defaults.Arch},
+	} {
+		if strings.TrimSpace(*field.dst) == "" {
+			*field.dst = strings.TrimSpace(field.val)
+		}
+	}
+	if flags.ContentLength == nil {
+		flags.ContentLength = &defaults.ContentLength
+	}
 
-	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		a.logger.WithFields(map[string]any{
-			"status": resp.StatusCode,
-			"body":   strings.TrimSpace(string(respBody)),
-		}).Error("upload failed")
+	if len(flags.Files) > 0 || len(defaults.Files) == 0 {
 		return nil
 	}
 
-	a.logger.WithFields(map[string]any{
-		"files":       len(flags.Files),
-		"app":         flags.AppName,
-		"version":     flags.Version,
-		"uploaded_id": extractUploadedID(respBody),
-	}).Info("Upload completed")
+	base := ""
+	if settings.ProjectPath != "" && strings.HasPrefix(settings.Origin("upload.files"), "project ") {
+		base = filepath.Dir(settings.ProjectPath)
+	}
+	for _, pattern := range defaults.Files {
+		pattern = strings.TrimSpace(pattern)
+		if base != "" && !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(base, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("upload.files: %w", err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("upload.files: no files match %s", pattern)
+		}
+		flags.Files = append(flags.Files, matches...)
+	}
 
+	a.logger.WithFields(map[string]any{
+		"files":  len(flags.Files),
+		"source": settings.Origin("upload.files"),
+	}).Debug("Using upload files from config")
 	return nil
 }
 
-func buildUploadBody(filePaths []string, dataField string) (io.Reader, string) {
+type uploadPart struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+	// Size reports the part length; it is nil when that is not known
+	// before the part is read.
+	Size func() (int64, error)
+}
+
+func fileParts(paths []string) []uploadPart {
+	parts := make([]uploadPart, 0, len(paths))
+	for _, path := range paths {
+		cleanPath := strings.TrimSpace(path)
+		parts = append(parts, uploadPart{
+			Name: filepath.Base(cleanPath),
+			Open: func() (io.ReadCloser, error) {
+				if cleanPath == "" {
+					return nil, errors.New("file path cannot be empty")
+				}
+				return os.Open(cleanPath)
+			},
+			Size: func() (int64, error) {
+				info, err := os.Stat(cleanPath)
+				if err != nil {
+					return 0, err
+				}
+				if !info.Mode().IsRegular() {
+					return 0, fmt.Errorf("%s is not a regular file", cleanPath)
+				}
+				return info.Size(), nil
+			},
+		})
+	}
+	return parts
+}
+
+func (c *apiClient) upload(parts []uploadPart, payload uploadData) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.contentLength {
+		return c.do(http.MethodPost, "/upload", nil, func() (io.Reader, string) {
+			return buildUploadBody(c.ctx, parts, string(payloadJSON), "", c.limiter)
+		})
+	}
+
+	// The length is computed with the same boundary the body is written
+	// with, so the two always agree.
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	size, err := uploadBodyLength(parts, string(payloadJSON), boundary)
+	if err != nil {
+		return nil, fmt.Errorf("compute upload size: %w", err)
+	}
+	return c.do(http.MethodPost, "/upload", nil, func() (io.Reader, string) {
+		body, contentType := buildUploadBody(c.ctx, parts, string(payloadJSON), boundary, c.limiter)
+		return &sizedReader{Reader: body, size: size}, contentType
+	})
+}
+
+// buildUploadBody streams parts as a multipart body. An empty boundary picks
+// a random one. File contents are read through limiter, which may be nil.
+func buildUploadBody(ctx context.Context, parts []uploadPart, dataField, boundary string, limiter *rateLimiter) (io.Reader, string) {
 	pr, pw := io.Pipe()
 	writer := multipart.NewWriter(pw)
+	if boundary != "" {
+		if err := writer.SetBoundary(boundary); err != nil {
+			_ = pw.CloseWithError(err)
+		}
+	}
 	contentType := writer.FormDataContentType()
 
 	go func() {
-		for _, path := range filePaths {
-			if err := appendFilePart(writer, path); err != nil {
+		for _, part := range parts {
+			if err := appendPart(ctx, writer, part, limiter); err != nil {
 				_ = pw.CloseWithError(err)
 				return
 			}
@@ -155,38 +319,107 @@ func buildUploadBody(filePaths []string, dataField string) (io.Reader, string) {
 	return pr, contentType
 }
 
-func appendFilePart(writer *multipart.Writer, path string) error {
-	cleanPath := strings.TrimSpace(path)
-	if cleanPath == "" {
-		return errors.New("file path cannot be empty")
+// uploadBodyLength returns the exact size of the body buildUploadBody writes
+// for the same arguments: the multipart framing is rendered without file
+// contents and the file sizes are added to it.
+func uploadBodyLength(parts []uploadPart, dataField, boundary string) (int64, error) {
+	var framing byteCounter
+	writer := multipart.NewWriter(&framing)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, err
 	}
 
-	file, err := os.Open(cleanPath)
+	total := int64(0)
+	for _, part := range parts {
+		if part.Size == nil {
+			return 0, fmt.Errorf("size of %s is not known in advance", part.Name)
+		}
+		size, err := part.Size()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := writer.CreateFormFile("file", part.Name); err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	if err := writer.WriteField("data", dataField); err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	return total + int64(framing), nil
+}
+
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+func appendPart(ctx context.Context, writer *multipart.Writer, part uploadPart, limiter *rateLimiter) error {
+	src, err := part.Open()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer src.Close()
 
-	part, err := writer.CreateFormFile("file", filepath.Base(cleanPath))
+	dst, err := writer.CreateFormFile("file", part.Name)
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(part, file)
+	_, err = io.Copy(dst, limiter.reader(ctx, src))
 	return err
 }
 
+// resumeFlags may be given together with --resume; everything else comes
+// from the saved state.
+var resumeFlags = map[string]bool{"--resume": true, "--preflight": true, "--content-length": true}
+
 func parseUploadFlags(args []string) (uploadFlags, error) {
 	var out uploadFlags
+	seen, err := parseUploadArgs(args, &out)
+	if err != nil {
+		return uploadFlags{}, err
+	}
+
+	if out.Resume != "" {
+		for name := range seen {
+			if !resumeFlags[name] {
+				return uploadFlags{}, fmt.Errorf("%s cannot be combined with --resume, which reuses the saved upload", name)
+			}
+		}
+	}
+
+	if err := applyUploadEnv(&out, seen); err != nil {
+		return uploadFlags{}, err
+	}
+
+	if err := validateChangelogInputMode(out); err != nil {
+		return uploadFlags{}, err
+	}
+
+	return out, nil
+}
+
+// parseUploadArgs fills out from args and returns the names of the flags
+// that were given, so env fallbacks do not override them.
+func parseUploadArgs(args []string, out *uploadFlags) (map[string]bool, error) {
+	seen := map[string]bool{}
 	for i := 0; i < len(args); i++ {
 		arg := strings.TrimSpace(args[i])
+		name, _, _ := strings.Cut(arg, "=")
+		seen[name] = true
 		switch {
 		case arg == "-h" || arg == "--help" || arg == "help":
-			return uploadFlags{}, errUploadHelp
+			return nil, errUploadHelp
 		case arg == "--app":
 			val, consumed, err := requireValue(args, i, "--app")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.AppName = val
 			i += consumed
@@ -195,7 +428,7 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--file":
 			val, consumed, err := requireValue(args, i, "--file")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Files = append(out.Files, val)
 			i += consumed
@@ -204,7 +437,7 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--version":
 			val, consumed, err := requireValue(args, i, "--version")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Version = val
 			i += consumed
@@ -213,7 +446,7 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--channel":
 			val, consumed, err := requireValue(args, i, "--channel")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Channel = val
 			i += consumed
@@ -222,7 +455,7 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--platform":
 			val, consumed, err := requireValue(args, i, "--platform")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Platform = val
 			i += consumed
@@ -231,7 +464,7 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--arch":
 			val, consumed, err := requireValue(args, i, "--arch")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Arch = val
 			i += consumed
@@ -240,46 +473,46 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--publish":
 			val, consumed, err := parseBoolValue(args, i, "--publish")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Publish = val
 			i += consumed
 		case strings.HasPrefix(arg, "--publish="):
 			val, err := parseBool(strings.TrimPrefix(arg, "--publish="), "--publish")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Publish = val
 		case arg == "--critical":
 			val, consumed, err := parseBoolValue(args, i, "--critical")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Critical = val
 			i += consumed
 		case strings.HasPrefix(arg, "--critical="):
 			val, err := parseBool(strings.TrimPrefix(arg, "--critical="), "--critical")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Critical = val
 		case arg == "--intermediate":
 			val, consumed, err := parseBoolValue(args, i, "--intermediate")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Intermediate = val
 			i += consumed
 		case strings.HasPrefix(arg, "--intermediate="):
 			val, err := parseBool(strings.TrimPrefix(arg, "--intermediate="), "--intermediate")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Intermediate = val
 		case arg == "--changelog":
 			val, consumed, err := requireValue(args, i, "--changelog")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.Changelog = val
 			i += consumed
@@ -288,7 +521,7 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--changelog-file":
 			val, consumed, err := requireValue(args, i, "--changelog-file")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.ChangelogFile = val
 			i += consumed
@@ -297,26 +530,57 @@ func parseUploadFlags(args []string) (uploadFlags, error) {
 		case arg == "--changelog-stdin":
 			val, consumed, err := parseBoolValue(args, i, "--changelog-stdin")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.ChangelogStdin = val
 			i += consumed
 		case strings.HasPrefix(arg, "--changelog-stdin="):
 			val, err := parseBool(strings.TrimPrefix(arg, "--changelog-stdin="), "--changelog-stdin")
 			if err != nil {
-				return uploadFlags{}, err
+				return nil, err
 			}
 			out.ChangelogStdin = val
+		case arg == "--preflight":
+			val, consumed, err := parseBoolValue(args, i, "--preflight")
+			if err != nil {
+				return nil, err
+			}
+			out.Preflight = val
+			i += consumed
+		case strings.HasPrefix(arg, "--preflight="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--preflight="), "--preflight")
+			if err != nil {
+				return nil, err
+			}
+			out.Preflight = val
+		case arg == "--resume":
+			val, consumed, err := requireValue(args, i, "--resume")
+			if err != nil {
+				return nil, err
+			}
+			out.Resume = val
+			i += consumed
+		case strings.HasPrefix(arg, "--resume="):
+			out.Resume = strings.TrimPrefix(arg, "--resume=")
+		case arg == "--content-length":
+			val, consumed, err := parseBoolValue(args, i, "--content-length")
+			if err != nil {
+				return nil, err
+			}
+			out.ContentLength = &val
+			i += consumed
+		case strings.HasPrefix(arg, "--content-length="):
+			val, err := parseBool(strings.TrimPrefix(arg, "--content-length="), "--content-length")
+			if err != nil {
+				return nil, err
+			}
+			out.ContentLength = &val
 		default:
-			return uploadFlags{}, fmt.Errorf("unknown upload flag: %s", arg)
+			return nil, fmt.Errorf("unknown upload flag: %s", arg)
 		}
 	}
 
-	if err := validateChangelogInputMode(out); err != nil {
-		return uploadFlags{}, err
-	}
-
-	return out, nil
+	return seen, nil
 }
 
 func validateChangelogInputMode(flags uploadFlags) error {
@@ -424,6 +688,14 @@ func parseBool(value, name string) (bool, error) {
 	return parsed, nil
 }
 
+func parseInt(value, name string) (int, error) {
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer value for %s: %q", name, value)
+	}
+	return parsed, nil
+}
+
 func (a *App) printUploadUsage() {
 	_, _ = fmt.Fprintln(a.out, `faynosync upload
 
@@ -442,5 +714,8 @@ Upload flags:
   --intermediate[=true|false]
   --changelog <text>
   --changelog-file <path>
-  --changelog-stdin`)
+  --changelog-stdin
+  --preflight[=true|false]  check upload permission via /whoami before streaming files
+  --resume <state>       repeat an interrupted upload from its saved state file
+  --content-length[=true|false]  send Content-Length instead of chunked encoding (default: upload.content_length)`)
 }
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// partialCheckTimeout bounds the lookup for a half-created version after an
+// interrupt, since the user is waiting for the process to exit.
+const partialCheckTimeout = 10 * time.Second
+
+// uploadState is saved when an upload is interrupted. The server takes an
+// upload in one request, so resuming sends the same files and metadata again
+// once it is clear the files did not change and no record was left behind.
+type uploadState struct {
+	CreatedAt time.Time   `json:"created_at"`
+	Reason    string      `json:"reason"`
+	Server    string      `json:"server"`
+	Profile   string      `json:"profile"`
+	Payload   uploadData  `json:"payload"`
+	Files     []stateFile `json:"files"`
+}
+
+type stateFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func newUploadState(client *apiClient, paths []string, payload uploadData, reason error) (uploadState, error) {
+	state := uploadState{
+		CreatedAt: time.Now().UTC(),
+		Reason:    reason.Error(),
+		Server:    client.server,
+		Profile:   client.profile,
+		Payload:   payload,
+	}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return uploadState{}, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return uploadState{}, err
+		}
+		state.Files = append(state.Files, stateFile{Path: abs, Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return state, nil
+}
+
+// saveUploadState writes state to path, or to a new file in the uploads
+// directory next to the stored credentials when path is empty.
+func saveUploadState(path string, state uploadState) (string, error) {
+	if path == "" {
+		dir, err := recordDir("uploads")
+		if err != nil {
+			return "", err
+		}
+		name := fmt.Sprintf("%s-%s-%s.json", safeFileName(state.Payload.AppName), safeFileName(state.Payload.Version), state.CreatedAt.Format("20060102T150405Z"))
+		path = filepath.Join(dir, name)
+	}
+
+	out, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func loadUploadState(path string) (uploadState, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return uploadState{}, err
+	}
+
+	var state uploadState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return uploadState{}, fmt.Errorf("parse upload state: %w", err)
+	}
+	if len(state.Files) == 0 {
+		return uploadState{}, fmt.Errorf("upload state %s lists no files", path)
+	}
+	return state, nil
+}
+
+func (s uploadState) paths() []string {
+	out := make([]string, 0, len(s.Files))
+	for _, file := range s.Files {
+		out = append(out, file.Path)
+	}
+	return out
+}
+
+// checkFiles makes sure a resumed upload sends the same bytes that were
+// being sent when it was interrupted.
+func (s uploadState) checkFiles() error {
+	for _, file := range s.Files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return err
+		}
+		if info.Size() != file.Size || !info.ModTime().Equal(file.ModTime) {
+			return fmt.Errorf("%s changed since the upload was interrupted, start a new upload instead", file.Path)
+		}
+	}
+	return nil
+}
+
+// existingRecord finds the version the upload would create, if the server
+// already has it.
+func (c *apiClient) existingRecord(payload uploadData) (versionRecord, bool, error) {
+	records, err := c.searchVersions(versionQuery{
+		AppName:  payload.AppName,
+		Channel:  payload.Channel,
+		Platform: payload.Platform,
+		Arch:     payload.Arch,
+	})
+	if err != nil {
+		return versionRecord{}, false, err
+	}
+	for _, record := range records {
+		if record.Version == payload.Version {
+			return record, true, nil
+		}
+	}
+	return versionRecord{}, false, nil
+}
+
+// interruptedUpload saves the state for --resume and reports whether the
+// server created a version before the request was cut off.
+func (a *App) interruptedUpload(client *apiClient, paths []string, payload uploadData, statePath string) error {
+	cause := context.Cause(a.ctx)
+
+	state, err := newUploadState(client, paths, payload, cause)
+	if err == nil {
+		statePath, err = saveUploadState(statePath, state)
+	}
+	if err != nil {
+		a.logger.WithError(err).Warn("Could not save upload state")
+		statePath = ""
+	}
+
+	// The run context is already cancelled, so the lookup gets its own.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(a.ctx), partialCheckTimeout)
+	defer cancel()
+	check := *client
+	check.ctx = ctx
+	check.retries = 0
+
+	record, found, err := check.existingRecord(payload)
+	switch {
+	case err != nil:
+		a.logger.WithError(err).Warn("Could not check the server for a partially created version")
+	case found:
+		a.logger.WithFields(map[string]any{
+			"id":      record.ID,
+			"app":     record.AppName,
+			"version": record.Version,
+			"channel": record.Channel,
+		}).Warn("The server created this version before the interrupt and it may be incomplete, remove it with: faynosync delete version")
+	default:
+		a.logger.Info("No version was created on the server")
+	}
+
+	if statePath == "" {
+		return fmt.Errorf("upload %w", cause)
+	}
+	return fmt.Errorf("upload %w, resume with: faynosync upload --resume %s", cause, statePath)
+}
+
+// checkResume refuses to resume against another server or profile, or when
+// the version already exists, which would otherwise fail or duplicate the
+// release.
+func checkResume(client *apiClient, state uploadState) error {
+	if state.Server != client.server {
+		return fmt.Errorf("the upload was interrupted on %s, but the profile uses %s", state.Server, client.server)
+	}
+	if state.Profile != client.profile {
+		return fmt.Errorf("the upload was interrupted with profile %q, but the active profile is %q, pass --profile %s", state.Profile, client.profile, state.Profile)
+	}
+
+	record, found, err := client.existingRecord(state.Payload)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("%s %s already exists on channel %q (id %s) and may be incomplete, delete it with: faynosync delete version --app %s --version %s --channel %s, then resume again",
+			record.AppName, record.Version, record.Channel, record.ID, record.AppName, record.Version, record.Channel)
+	}
+	return nil
+}
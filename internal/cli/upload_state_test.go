@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterruptedUploadSavesStateAndResumes(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	artifact := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(artifact, make([]byte, 256<<10), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	// The version shows up on the server as if the request got far enough
+	// to create it before the connection was cut.
+	fs.records = []versionRecord{{ID: "half", AppName: "myapp", Version: "1.0.0", Channel: "beta"}}
+
+	ctx, cancel := context.WithCancelCause(t.Context())
+	time.AfterFunc(200*time.Millisecond, func() { cancel(&interruptError{signal: os.Interrupt}) })
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	err := app.Run(ctx, []string{"--limit-rate", "64K", "upload", "--app", "myapp", "--version", "1.0.0", "--channel", "beta", "--file", artifact, "--changelog", "notes"})
+	if err == nil {
+		t.Fatal("expected the upload to be interrupted")
+	}
+	if code := ExitCode(err); code != 130 {
+		t.Fatalf("expected exit code 130, got %d for %v", code, err)
+	}
+	if !strings.Contains(out.String(), "may be incomplete") {
+		t.Fatalf("expected the half-created version to be reported, got:\n%s", out.String())
+	}
+
+	_, statePath, ok := strings.Cut(err.Error(), "--resume ")
+	if !ok {
+		t.Fatalf("expected a resume hint, got %v", err)
+	}
+	state, err := loadUploadState(statePath)
+	if err != nil {
+		t.Fatalf("load upload state: %v", err)
+	}
+	if state.Payload.Changelog != "notes" || len(state.Files) != 1 {
+		t.Fatalf("unexpected upload state: %+v", state)
+	}
+
+	app = New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"upload", "--resume", statePath, "--app", "other"}); err == nil || !strings.Contains(err.Error(), "--app cannot be combined") {
+		t.Fatalf("expected --resume to reject --app, got %v", err)
+	}
+	if err := app.Run(t.Context(), []string{"--profile", "other", "upload", "--resume", statePath}); err == nil || !strings.Contains(err.Error(), `active profile is "other"`) {
+		t.Fatalf("expected --resume to refuse another profile, got %v", err)
+	}
+	if info, err := os.Stat(filepath.Dir(statePath)); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0o700) {
+		t.Fatalf("expected a private uploads directory, got %v, %v", info, err)
+	}
+	if err := app.Run(t.Context(), []string{"upload", "--resume", statePath}); err == nil || !strings.Contains(err.Error(), "delete version") {
+		t.Fatalf("expected resume to refuse while the version exists, got %v", err)
+	}
+
+	fs.mu.Lock()
+	fs.records = nil
+	fs.requests = nil
+	fs.mu.Unlock()
+	if err := app.Run(t.Context(), []string{"upload", "--resume", statePath}); err != nil {
+		t.Fatalf("resume returned error: %v", err)
+	}
+	uploads := fs.requestsTo("/upload")
+	if len(uploads) != 1 || !strings.Contains(uploads[0].Body, `"changelog":"notes"`) {
+		t.Fatalf("expected the saved upload to be sent again, got %+v", uploads)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Fatalf("expected the state file to be removed after resuming, got %v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code := ExitCode(nil); code != 0 {
+		t.Fatalf("expected 0 without an error, got %d", code)
+	}
+	if code := ExitCode(os.ErrNotExist); code != 1 {
+		t.Fatalf("expected 1 for a plain error, got %d", code)
+	}
+	if code := ExitCode(&interruptError{signal: os.Interrupt}); code != 130 {
+		t.Fatalf("expected 130 after SIGINT, got %d", code)
+	}
+}
@@ -2,9 +2,14 @@ package cli
 
 import (
 	"bytes"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"faynoSync-cli/internal/config"
 )
 
 func TestParseUploadFlagsSupportsChangelogFile(t *testing.T) {
@@ -102,3 +107,119 @@ func TestExtractUploadedIDFromNestedResponse(t *testing.T) {
 		t.Fatalf("unexpected uploaded id:\nwant: %q\ngot:  %q", want, got)
 	}
 }
+
+func TestParseUploadFlagsFallsBackToEnv(t *testing.T) {
+	t.Setenv("FAYNOSYNC_APP", "env-app")
+	t.Setenv("FAYNOSYNC_CHANNEL", "nightly")
+	t.Setenv("FAYNOSYNC_FILES", "a.zip, b.zip")
+	t.Setenv("FAYNOSYNC_PUBLISH", "true")
+	t.Setenv("FAYNOSYNC_CRITICAL", "true")
+	t.Setenv("FAYNOSYNC_CHANGELOG", "from env")
+
+	flags, err := parseUploadFlags([]string{"--channel", "beta", "--critical=false", "--changelog-file", "notes.md"})
+	if err != nil {
+		t.Fatalf("parseUploadFlags returned error: %v", err)
+	}
+
+	if flags.AppName != "env-app" || !flags.Publish {
+		t.Fatalf("expected env fallbacks, got %+v", flags)
+	}
+	if len(flags.Files) != 2 || flags.Files[1] != "b.zip" {
+		t.Fatalf("expected files from FAYNOSYNC_FILES, got %v", flags.Files)
+	}
+	if flags.Channel != "beta" || flags.Critical {
+		t.Fatalf("expected flags to win over env, got %+v", flags)
+	}
+	if flags.Changelog != "" || flags.ChangelogFile != "notes.md" {
+		t.Fatalf("expected changelog flag to disable changelog env, got %+v", flags)
+	}
+}
+
+func TestParseUploadFlagsNamesInvalidEnv(t *testing.T) {
+	t.Setenv("FAYNOSYNC_PUBLISH", "maybe")
+
+	_, err := parseUploadFlags(nil)
+	if err == nil || !strings.Contains(err.Error(), "FAYNOSYNC_PUBLISH") {
+		t.Fatalf("expected error naming FAYNOSYNC_PUBLISH, got %v", err)
+	}
+}
+
+func TestUploadBodyLengthMatchesBody(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for name, size := range map[string]int{"app.tar.gz": 70000, "empty.bin": 0, "notes \"v2\".txt": 12} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), size), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+	parts := fileParts(paths)
+	data := `{"app_name":"myapp","changelog":"ünïcode"}`
+
+	want, err := uploadBodyLength(parts, data, "fixedboundary")
+	if err != nil {
+		t.Fatalf("uploadBodyLength returned error: %v", err)
+	}
+	body, _ := buildUploadBody(t.Context(), parts, data, "fixedboundary", nil)
+	got, err := io.Copy(io.Discard, body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if got != want {
+		t.Fatalf("computed length %d, body has %d bytes", want, got)
+	}
+}
+
+func TestUploadSendsContentLength(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	var contentLength int64
+	var chunked bool
+	fs.handle("/upload", func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		chunked = len(r.TransferEncoding) > 0
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"uploaded_id":"1"}`))
+	})
+
+	artifact := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(artifact, make([]byte, 4096), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+
+	if err := app.Run(t.Context(), []string{"upload", "--app", "myapp", "--file", artifact}); err != nil {
+		t.Fatalf("upload returned error: %v", err)
+	}
+	if !chunked || contentLength != -1 {
+		t.Fatalf("expected a chunked upload by default, got length %d", contentLength)
+	}
+
+	if err := app.Run(t.Context(), []string{"upload", "--app", "myapp", "--file", artifact, "--content-length"}); err != nil {
+		t.Fatalf("upload --content-length returned error: %v", err)
+	}
+	if chunked || contentLength <= 4096 {
+		t.Fatalf("expected Content-Length, got length %d chunked=%v", contentLength, chunked)
+	}
+
+	if _, err := config.Init("", config.Default()); err != nil {
+		t.Fatalf("init config: %v", err)
+	}
+	if err := app.Run(t.Context(), []string{"config", "set", "upload.content_length", "true"}); err != nil {
+		t.Fatalf("config set returned error: %v", err)
+	}
+	if err := app.Run(t.Context(), []string{"upload", "--app", "myapp", "--file", artifact, "--content-length=false"}); err != nil {
+		t.Fatalf("upload returned error: %v", err)
+	}
+	if !chunked {
+		t.Fatal("expected --content-length=false to override upload.content_length")
+	}
+	if err := app.Run(t.Context(), []string{"upload", "--app", "myapp", "--file", artifact}); err != nil {
+		t.Fatalf("upload returned error: %v", err)
+	}
+	if chunked {
+		t.Fatal("expected upload.content_length to enable Content-Length")
+	}
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const searchPageLimit = 100
+
+type artifactRecord struct {
+	Link     string `json:"link"`
+	Platform string `json:"platform"`
+	Arch     string `json:"arch"`
+	Package  string `json:"package"`
+}
+
+type changelogRecord struct {
+	Version string `json:"version"`
+	Changes string `json:"changes"`
+	Date    string `json:"date"`
+}
+
+type versionRecord struct {
+	ID           string            `json:"id"`
+	AppName      string            `json:"app_name"`
+	Version      string            `json:"version"`
+	Channel      string            `json:"channel"`
+	Published    bool              `json:"published"`
+	Critical     bool              `json:"critical"`
+	Intermediate bool              `json:"intermediate"`
+	Artifacts    []artifactRecord  `json:"artifacts"`
+	Changelog    []changelogRecord `json:"changelog"`
+	UpdatedAt    string            `json:"updated_at"`
+}
+
+type searchResponse struct {
+	Items []versionRecord `json:"items"`
+	Total int             `json:"total"`
+}
+
+type versionQuery struct {
+	AppName  string
+	Channel  string
+	Platform string
+	Arch     string
+}
+
+func (q versionQuery) values() url.Values {
+	out := url.Values{}
+	if q.AppName != "" {
+		out.Set("app_name", q.AppName)
+	}
+	if q.Channel != "" {
+		out.Set("channel", q.Channel)
+	}
+	if q.Platform != "" {
+		out.Set("platform", q.Platform)
+	}
+	if q.Arch != "" {
+		out.Set("arch", q.Arch)
+	}
+	return out
+}
+
+func (c *apiClient) searchVersions(q versionQuery) ([]versionRecord, error) {
+	if strings.TrimSpace(q.AppName) == "" {
+		return nil, fmt.Errorf("--app is required")
+	}
+
+	var out []versionRecord
+	for page := 1; ; page++ {
+		values := q.values()
+		values.Set("page", strconv.Itoa(page))
+		values.Set("limit", strconv.Itoa(searchPageLimit))
+
+		var resp searchResponse
+		if err := c.getJSON("/search", values, &resp); err != nil {
+			return nil, err
+		}
+
+		out = append(out, resp.Items...)
+		if len(resp.Items) < searchPageLimit || (resp.Total > 0 && len(out) >= resp.Total) {
+			break
+		}
+	}
+
+	return out, nil
+}
+
+func (c *apiClient) findVersion(q versionQuery, version string) (versionRecord, error) {
+	records, err := c.searchVersions(q)
+	if err != nil {
+		return versionRecord{}, err
+	}
+
+	var matches []versionRecord
+	for _, record := range records {
+		if record.Version == version {
+			matches = append(matches, record)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return versionRecord{}, fmt.Errorf("version %s of %s not found on channel %q", version, q.AppName, q.Channel)
+	case 1:
+		return matches[0], nil
+	default:
+		return versionRecord{}, fmt.Errorf("version %s of %s is ambiguous (%d matches), narrow it down with --channel, --platform or --arch", version, q.AppName, len(matches))
+	}
+}
+
+func (r versionRecord) matchingArtifacts(platform, arch, pkg string) []int {
+	var out []int
+	for i, artifact := range r.Artifacts {
+		if platform != "" && artifact.Platform != platform {
+			continue
+		}
+		if arch != "" && artifact.Arch != arch {
+			continue
+		}
+		if pkg != "" && strings.TrimPrefix(artifact.Package, ".") != strings.TrimPrefix(pkg, ".") {
+			continue
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+func (r versionRecord) changes() string {
+	for _, entry := range r.Changelog {
+		if entry.Version == r.Version {
+			return entry.Changes
+		}
+	}
+	if len(r.Changelog) > 0 {
+		return r.Changelog[len(r.Changelog)-1].Changes
+	}
+	return ""
+}
+
+func writeVersionSummary(w io.Writer, record versionRecord) {
+	_, _ = fmt.Fprintf(w, "  app:          %s\n", record.AppName)
+	_, _ = fmt.Fprintf(w, "  version:      %s\n", record.Version)
+	_, _ = fmt.Fprintf(w, "  channel:      %s\n", record.Channel)
+	_, _ = fmt.Fprintf(w, "  published:    %t\n", record.Published)
+	_, _ = fmt.Fprintf(w, "  critical:     %t\n", record.Critical)
+	_, _ = fmt.Fprintf(w, "  intermediate: %t\n", record.Intermediate)
+	if len(record.Artifacts) == 0 {
+		_, _ = fmt.Fprintln(w, "  artifacts:    none")
+		return
+	}
+	_, _ = fmt.Fprintln(w, "  artifacts:")
+	for _, artifact := range record.Artifacts {
+		writeArtifactLine(w, artifact)
+	}
+}
+
+func writeArtifactLine(w io.Writer, artifact artifactRecord) {
+	_, _ = fmt.Fprintf(w, "    - %s/%s %s %s\n", artifact.Platform, artifact.Arch, artifact.Package, artifact.Link)
+}
+
+// The server has historically emitted both "ID"/"AppName" style keys and
+// snake_case ones; encoding/json matches keys case-insensitively but not
+// across underscores, so accept both spellings explicitly.
+func (r *versionRecord) UnmarshalJSON(raw []byte) error {
+	type plain versionRecord
+	var aux struct {
+		plain
+		LegacyAppName   string `json:"AppName"`
+		LegacyUpdatedAt string `json:"Updated_at"`
+	}
+	if err := json.Unmarshal(raw, &aux); err != nil {
+		return err
+	}
+
+	*r = versionRecord(aux.plain)
+	if r.AppName == "" {
+		r.AppName = aux.LegacyAppName
+	}
+	if r.UpdatedAt == "" {
+		r.UpdatedAt = aux.LegacyUpdatedAt
+	}
+	return nil
+}
+
+func compareVersions(a, b string) int {
+	left := splitVersion(a)
+	right := splitVersion(b)
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		if c := compareVersionPart(l, r); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func splitVersion(v string) []string {
+	return strings.FieldsFunc(strings.TrimPrefix(strings.TrimSpace(v), "v"), func(r rune) bool {
+		return r == '.' || r == '-' || r == '+' || r == '_'
+	})
+}
+
+func compareVersionPart(l, r string) int {
+	ln, lerr := strconv.Atoi(l)
+	rn, rerr := strconv.Atoi(r)
+	switch {
+	case lerr == nil && rerr == nil:
+		return cmp.Compare(ln, rn)
+	case l == "" && r != "":
+		// 1.0 sorts after 1.0-rc1, but before 1.0.1.
+		if rerr != nil {
+			return 1
+		}
+		return -1
+	case r == "" && l != "":
+		if lerr != nil {
+			return -1
+		}
+		return 1
+	case lerr == nil:
+		return 1
+	case rerr == nil:
+		return -1
+	default:
+		return strings.Compare(l, r)
+	}
+}
+
+func sortVersionsDesc(records []versionRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return compareVersions(records[i].Version, records[j].Version) > 0
+	})
+}
+
+type updateData struct {
+	ID           string `json:"id"`
+	AppName      string `json:"app_name"`
+	Version      string `json:"version"`
+	Channel      string `json:"channel"`
+	Publish      bool   `json:"publish"`
+	Critical     bool   `json:"critical"`
+	Intermediate bool   `json:"intermediate"`
+}
+
+func (c *apiClient) updateVersion(data updateData) error {
+	payloadJSON, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(http.MethodPost, "/apps/update", nil, func() (io.Reader, string) {
+		return buildUploadBody(c.ctx, nil, string(payloadJSON), "", nil)
+	})
+	return err
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type whoamiResponse struct {
+	Username    string                        `json:"username"`
+	Owner       string                        `json:"owner"`
+	IsAdmin     bool                          `json:"is_admin"`
+	Permissions map[string]resourcePermission `json:"permissions"`
+}
+
+type resourcePermission struct {
+	Create   bool     `json:"create"`
+	Delete   bool     `json:"delete"`
+	Edit     bool     `json:"edit"`
+	Download bool     `json:"download"`
+	Upload   bool     `json:"upload"`
+	Allowed  []string `json:"allowed"`
+}
+
+func (a *App) runWhoami(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "-h", "--help", "help":
+			a.printWhoamiUsage()
+			return nil
+		default:
+			return fmt.Errorf("unknown whoami flag: %s", args[0])
+		}
+	}
+
+	client, err := a.newAPIClient()
+	if err != nil {
+		return err
+	}
+
+	identity, err := client.whoami()
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.out, "user:         %s\n", identity.Username)
+	_, _ = fmt.Fprintf(a.out, "owner:        %s\n", identity.Owner)
+	_, _ = fmt.Fprintf(a.out, "admin:        %t\n", identity.IsAdmin)
+	_, _ = fmt.Fprintf(a.out, "server:       %s\n", client.server)
+	_, _ = fmt.Fprintf(a.out, "profile:      %s\n", client.profile)
+	_, _ = fmt.Fprintf(a.out, "token source: %s\n", client.tokenSource)
+	if expiresAt, ok := tokenExpiry(client.token); ok {
+		_, _ = fmt.Fprintf(a.out, "token expiry: %s (%s)\n", expiresAt.Format(time.RFC3339), describeRemaining(time.Until(expiresAt)))
+	} else {
+		_, _ = fmt.Fprintln(a.out, "token expiry: unknown")
+	}
+
+	if identity.IsAdmin {
+		_, _ = fmt.Fprintln(a.out, "permissions:  all (admin)")
+		return nil
+	}
+	if len(identity.Permissions) == 0 {
+		_, _ = fmt.Fprintln(a.out, "permissions:  none")
+		return nil
+	}
+
+	_, _ = fmt.Fprintln(a.out, "permissions:")
+	resources := make([]string, 0, len(identity.Permissions))
+	for resource := range identity.Permissions {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		_, _ = fmt.Fprintf(a.out, "  %-10s  %s\n", resource+":", identity.Permissions[resource].describe())
+	}
+	return nil
+}
+
+func (c *apiClient) whoami() (whoamiResponse, error) {
+	var out whoamiResponse
+	if err := c.getJSON("/whoami", nil, &out); err != nil {
+		return whoamiResponse{}, err
+	}
+	return out, nil
+}
+
+// preflightUpload fails fast when the server says the token may not upload to
+// app. Servers without /whoami are tolerated so the check stays optional.
+func (a *App) preflightUpload(client *apiClient, app string) error {
+	identity, err := client.whoami()
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusMethodNotAllowed) {
+			a.logger.Warn("Server does not support /whoami, skipping upload pre-flight")
+			return nil
+		}
+		return fmt.Errorf("upload pre-flight: %w", err)
+	}
+
+	if !identity.canUpload(app) {
+		return fmt.Errorf("upload pre-flight: user %q may not upload to app %q", identity.Username, app)
+	}
+
+	a.logger.WithFields(map[string]any{
+		"user": identity.Username,
+		"app":  app,
+	}).Debug("Upload pre-flight passed")
+	return nil
+}
+
+func (w whoamiResponse) canUpload(app string) bool {
+	if w.IsAdmin {
+		return true
+	}
+	perm, ok := w.Permissions["apps"]
+	if !ok || !perm.Upload {
+		return false
+	}
+	return len(perm.Allowed) == 0 || slices.Contains(perm.Allowed, app)
+}
+
+func (p resourcePermission) describe() string {
+	var actions []string
+	for _, action := range []struct {
+		name    string
+		allowed bool
+	}{
+		{"create", p.Create},
+		{"edit", p.Edit},
+		{"delete", p.Delete},
+		{"download", p.Download},
+		{"upload", p.Upload},
+	} {
+		if action.allowed {
+			actions = append(actions, action.name)
+		}
+	}
+
+	out := "none"
+	if len(actions) > 0 {
+		out = strings.Join(actions, ", ")
+	}
+	if len(p.Allowed) > 0 {
+		out += " (allowed: " + strings.Join(p.Allowed, ", ") + ")"
+	}
+	return out
+}
+
+func describeRemaining(d time.Duration) string {
+	if d <= 0 {
+		return "expired " + (-d).Round(time.Second).String() + " ago"
+	}
+	return "in " + d.Round(time.Second).String()
+}
+
+func (a *App) printWhoamiUsage() {
+	_, _ = fmt.Fprintln(a.out, `faynosync whoami
+
+Usage:
+  faynosync whoami
+
+Shows the authenticated user, owner, token source and expiry, and the
+permissions the server grants to the token.`)
+}
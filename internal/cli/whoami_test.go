@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveIdentity(fs *fakeServer, identity whoamiResponse) {
+	fs.handle("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(identity)
+	})
+}
+
+func TestWhoamiPrintsIdentityAndPermissions(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	t.Setenv("FAYNOSYNC_TOKEN", testJWT(time.Now().Add(2*time.Hour)))
+	serveIdentity(fs, whoamiResponse{
+		Username: "ci-bot",
+		Owner:    "acme",
+		Permissions: map[string]resourcePermission{
+			"apps": {Upload: true, Download: true, Allowed: []string{"myapp"}},
+		},
+	})
+
+	out := bytes.NewBuffer(nil)
+	app := New(bytes.NewBuffer(nil), out)
+	if err := app.Run(t.Context(), []string{"whoami"}); err != nil {
+		t.Fatalf("whoami returned error: %v", err)
+	}
+
+	for _, want := range []string{"user:         ci-bot", "owner:        acme", "token source: FAYNOSYNC_TOKEN", "token expiry: ", "apps:       download, upload (allowed: myapp)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestUploadPreflightRejectsAppWithoutPermission(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	serveIdentity(fs, whoamiResponse{
+		Username: "ci-bot",
+		Permissions: map[string]resourcePermission{
+			"apps": {Upload: true, Allowed: []string{"otherapp"}},
+		},
+	})
+
+	artifact := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(artifact, []byte("payload"), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	err := app.Run(t.Context(), []string{"upload", "--app", "myapp", "--file", artifact, "--preflight"})
+	if err == nil || !strings.Contains(err.Error(), "may not upload") {
+		t.Fatalf("expected pre-flight rejection, got %v", err)
+	}
+	if got := fs.requestsTo("/upload"); len(got) != 0 {
+		t.Fatalf("expected no upload after failed pre-flight, got %d", len(got))
+	}
+}
+
+func TestUploadPreflightToleratesServersWithoutWhoami(t *testing.T) {
+	fs := newFakeServer(t, nil)
+	fs.handle("/whoami", http.NotFound)
+
+	artifact := filepath.Join(t.TempDir(), "app.bin")
+	if err := os.WriteFile(artifact, []byte("payload"), 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+
+	app := New(bytes.NewBuffer(nil), bytes.NewBuffer(nil))
+	if err := app.Run(t.Context(), []string{"upload", "--app", "myapp", "--file", artifact, "--preflight"}); err != nil {
+		t.Fatalf("upload returned error: %v", err)
+	}
+	if got := fs.requestsTo("/upload"); len(got) != 1 {
+		t.Fatalf("expected upload to proceed, got %d requests", len(got))
+	}
+}
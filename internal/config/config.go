@@ -14,39 +14,153 @@ const (
 	DefaultServer = "https://example.com"
 	DefaultOwner  = "example"
 
-	EnvToken   = "FAYNOSYNC_TOKEN"
-	EnvURL     = "FAYNOSYNC_URL"
-	EnvAccount = "FAYNOSYNC_ACCOUNT"
+	DefaultProfile = "default"
+
+	EnvToken     = "FAYNOSYNC_TOKEN"
+	EnvURL       = "FAYNOSYNC_URL"
+	EnvAccount   = "FAYNOSYNC_ACCOUNT"
+	EnvProfile   = "FAYNOSYNC_PROFILE"
+	EnvTokenFile = "FAYNOSYNC_TOKEN_FILE"
+	EnvIDToken   = "FAYNOSYNC_ID_TOKEN"
+	EnvConfig    = "FAYNOSYNC_CONFIG"
+
+	DefaultOIDCEndpoint = "/auth/oidc/exchange"
+
+	TokenSourceEnv         = EnvToken
+	TokenSourceFile        = EnvTokenFile
+	TokenSourceHelper      = "credential helper"
+	TokenSourceCredentials = "stored credentials"
 )
 
+var errConfigNotFound = errors.New("config not found, run: faynosync init")
+
 type Config struct {
-	Server string `yaml:"server"`
-	Owner  string `yaml:"owner"`
+	Version  int                `yaml:"version"`
+	Include  Includes           `yaml:"include,omitempty"`
+	Current  string             `yaml:"current"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+type Profile struct {
+	Server           string `yaml:"server"`
+	Owner            string `yaml:"owner"`
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+	OIDC             OIDC   `yaml:"oidc,omitempty"`
+	Upload           Upload `yaml:"upload,omitempty"`
+	HTTP             HTTP   `yaml:"http,omitempty"`
+}
+
+type Upload struct {
+	App      string   `yaml:"app,omitempty"`
+	Channel  string   `yaml:"channel,omitempty"`
+	Platform string   `yaml:"platform,omitempty"`
+	Arch     string   `yaml:"arch,omitempty"`
+	Files    []string `yaml:"files,omitempty"`
+	// ContentLength sends uploads with a precomputed Content-Length
+	// instead of chunked encoding.
+	ContentLength bool `yaml:"content_length,omitempty"`
+}
+
+type HTTP struct {
+	Timeout         string            `yaml:"timeout,omitempty"`
+	ConnectTimeout  string            `yaml:"connect_timeout,omitempty"`
+	TLSTimeout      string            `yaml:"tls_timeout,omitempty"`
+	ResponseTimeout string            `yaml:"response_timeout,omitempty"`
+	StallTimeout    string            `yaml:"stall_timeout,omitempty"`
+	Retries         int               `yaml:"retries,omitempty"`
+	LimitRate       string            `yaml:"limit_rate,omitempty"`
+	Proxy           string            `yaml:"proxy,omitempty"`
+	NoProxy         string            `yaml:"no_proxy,omitempty"`
+	CABundle        string            `yaml:"ca_bundle,omitempty"`
+	ClientCert      string            `yaml:"client_cert,omitempty"`
+	ClientKey       string            `yaml:"client_key,omitempty"`
+	MinTLS          string            `yaml:"min_tls,omitempty"`
+	Insecure        bool              `yaml:"insecure,omitempty"`
+	Headers         map[string]string `yaml:"headers,omitempty"`
+}
+
+type OIDC struct {
+	Endpoint  string `yaml:"endpoint,omitempty"`
+	TokenFile string `yaml:"token_file,omitempty"`
+	TokenEnv  string `yaml:"token_env,omitempty"`
 }
 
 type RuntimeConfig struct {
-	Token  string
-	Server string
-	Owner  string
+	Profile          string
+	Token            string
+	TokenSource      string
+	Server           string
+	Owner            string
+	CredentialHelper string
+	OIDC             OIDC
+	HTTP             HTTP
 }
 
 func Default() Config {
 	return Config{
-		Server: DefaultServer,
-		Owner:  DefaultOwner,
+		Version: CurrentVersion,
+		Current: DefaultProfile,
+		Profiles: map[string]Profile{
+			DefaultProfile: {
+				Server: DefaultServer,
+				Owner:  DefaultOwner,
+			},
+		},
 	}
 }
 
-func Path() (string, error) {
+// Path returns the config file location: path, which comes from --config,
+// then FAYNOSYNC_CONFIG, then the default location.
+func Path(path string) (string, error) {
+	if path = strings.TrimSpace(path); path != "" {
+		return path, nil
+	}
+	if path := strings.TrimSpace(os.Getenv(EnvConfig)); path != "" {
+		return path, nil
+	}
+	return defaultPath()
+}
+
+// defaultPath is $XDG_CONFIG_HOME/faynosync/config.yaml, or
+// ~/.faynosync/config.yaml. An existing legacy file keeps being used while
+// the XDG one does not exist.
+func defaultPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
+	legacy := filepath.Join(home, ".faynosync", "config.yaml")
+
+	xdgHome := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME"))
+	if xdgHome == "" || !filepath.IsAbs(xdgHome) {
+		return legacy, nil
+	}
+	xdg := filepath.Join(xdgHome, "faynosync", "config.yaml")
+	if !exists(xdg) && exists(legacy) {
+		return legacy, nil
+	}
+	return xdg, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Dir is the per-user directory for credentials and records. It does not
+// follow --config or FAYNOSYNC_CONFIG, so pointing the CLI at another config
+// file never moves stored tokens next to it.
+func Dir() (string, error) {
+	path, err := defaultPath()
+	if err != nil {
+		return "", err
+	}
 
-	return filepath.Join(home, ".faynosync", "config.yaml"), nil
+	return filepath.Dir(path), nil
 }
-func Init(cfg Config) (string, error) {
-	path, err := Path()
+
+func Init(path string, cfg Config) (string, error) {
+	path, err := Path(path)
 	if err != nil {
 		return "", err
 	}
@@ -62,94 +176,201 @@ func Init(cfg Config) (string, error) {
 	return path, nil
 }
 
-func Load() (Config, string, error) {
-	path, err := Path()
+// Load returns the effective config: includes are merged in and ${VAR}
+// references are expanded.
+func Load(path string) (Config, string, error) {
+	root, path, err := loadNode(path)
+	if err != nil || root == nil {
+		return Config{}, path, err
+	}
+
+	root, err = resolveIncludes(root, filepath.Dir(path), []string{path})
+	if err != nil {
+		return Config{}, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if err := expandNode(root, ""); err != nil {
+		return Config{}, "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := root.Decode(&cfg); err != nil {
+		return Config{}, "", fmt.Errorf("%s: %w", path, err)
+	}
+	cfg.Include = nil
+
+	return cfg, path, nil
+}
+
+// LoadRaw returns the config file without includes. ${VAR} references are
+// expanded where they resolve and left empty otherwise, so it never fails on
+// the environment; changes are saved through Update, which keeps them.
+func LoadRaw(path string) (Config, string, error) {
+	root, path, err := loadNode(path)
+	if err != nil || root == nil {
+		return Config{}, path, err
+	}
+
+	var cfg Config
+	if err := lenientCopy(root).Decode(&cfg); err != nil {
+		return Config{}, "", fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, path, nil
+}
+
+func loadNode(path string) (*yaml.Node, string, error) {
+	path, err := Path(path)
 	if err != nil {
-		return Config{}, "", err
+		return nil, "", err
 	}
 
 	raw, err := os.ReadFile(path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return Config{}, "", errors.New("config not found, run: faynosync init")
+			return nil, "", errConfigNotFound
 		}
-		return Config{}, "", err
+		return nil, "", err
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(raw, &cfg); err != nil {
-		return Config{}, "", err
+	// Older files are upgraded in memory only. Writing them back is left to
+	// config migrate and to commands that save the config under the lock.
+	migrated, _, err := Migrate(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
 	}
 
-	return cfg, path, nil
+	var doc yaml.Node
+	if err := yaml.Unmarshal(migrated, &doc); err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, path, nil
+	}
+	return doc.Content[0], path, nil
 }
 
 func SaveAt(path string, cfg Config) error {
+	cfg.Version = CurrentVersion
 	out, err := yaml.Marshal(cfg)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(path, out, 0o644)
-}
-
-func UpdateField(cfg *Config, key, value string) error {
-	switch key {
-	case "server":
-		cfg.Server = value
-	case "owner":
-		cfg.Owner = value
-	default:
-		return fmt.Errorf("unknown key: %s (allowed: server, owner)", key)
-	}
-
-	return nil
+	return writeFileAtomic(path, out, 0o600)
 }
 
 func Marshal(cfg Config) ([]byte, error) {
 	return yaml.Marshal(cfg)
 }
 
-func LoadRuntime() (RuntimeConfig, string, error) {
+func LoadRuntime(path, profile string) (RuntimeConfig, string, error) {
+	runtimeCfg, path, err := LoadServer(path, profile)
+	if err != nil {
+		return RuntimeConfig{}, path, err
+	}
+
 	token := strings.TrimSpace(os.Getenv(EnvToken))
+	source := TokenSourceEnv
 	if token == "" {
-		return RuntimeConfig{}, "", fmt.Errorf("%s is required", EnvToken)
+		source = TokenSourceFile
+		token, err = tokenFromFile()
+		if err != nil {
+			return RuntimeConfig{}, path, err
+		}
 	}
-
-	envServer := strings.TrimSpace(os.Getenv(EnvURL))
-	envOwner := strings.TrimSpace(os.Getenv(EnvAccount))
-	needsConfig := envServer == "" || envOwner == ""
-
-	cfg := Config{}
-	path := ""
-	if needsConfig {
-		var err error
-		cfg, path, err = Load()
+	if token == "" && runtimeCfg.CredentialHelper != "" {
+		source = TokenSourceHelper
+		token, err = HelperToken(runtimeCfg)
+		if err != nil {
+			return RuntimeConfig{}, path, err
+		}
+	}
+	if token == "" {
+		source = TokenSourceCredentials
+		token, err = StoredToken(runtimeCfg.Profile, runtimeCfg.Server)
 		if err != nil {
-			return RuntimeConfig{}, "", err
+			return RuntimeConfig{}, path, err
 		}
 	}
+	if token == "" {
+		return RuntimeConfig{}, path, fmt.Errorf("%s is required (or run: faynosync login)", EnvToken)
+	}
 
-	server := envServer
-	if server == "" {
-		server = strings.TrimSpace(cfg.Server)
+	runtimeCfg.Token = token
+	runtimeCfg.TokenSource = source
+	return runtimeCfg, path, nil
+}
+
+// TokenSource reports which source LoadRuntime would take the token from,
+// without running the credential helper. It returns "" when no source
+// provides a token.
+func TokenSource(runtimeCfg RuntimeConfig) (string, error) {
+	if strings.TrimSpace(os.Getenv(EnvToken)) != "" {
+		return TokenSourceEnv, nil
 	}
+	token, err := tokenFromFile()
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		return TokenSourceFile, nil
+	}
+	if runtimeCfg.CredentialHelper != "" {
+		return TokenSourceHelper, nil
+	}
+	token, err = StoredToken(runtimeCfg.Profile, runtimeCfg.Server)
+	if err != nil || token == "" {
+		return "", err
+	}
+	return TokenSourceCredentials, nil
+}
 
-	owner := envOwner
-	if owner == "" {
-		owner = strings.TrimSpace(cfg.Owner)
+func LoadServer(path, profile string) (RuntimeConfig, string, error) {
+	settings, err := Resolve(path, profile)
+	if err != nil {
+		return RuntimeConfig{}, "", err
+	}
+
+	if err := settings.Values.Validate(); err != nil {
+		return RuntimeConfig{}, settings.UserPath, err
+	}
+
+	server := strings.TrimSpace(settings.Values.Server)
+	owner := strings.TrimSpace(settings.Values.Owner)
+	if (server == "" || owner == "") && settings.userErr != nil {
+		return RuntimeConfig{}, settings.UserPath, settings.userErr
 	}
 
 	if server == "" {
-		return RuntimeConfig{}, path, errors.New("server is empty: set in config or via FAYNOSYNC_URL")
+		return RuntimeConfig{}, settings.UserPath, errors.New("server is empty: set in config or via FAYNOSYNC_URL")
 	}
 	if owner == "" {
-		return RuntimeConfig{}, path, errors.New("owner is empty: set in config or via FAYNOSYNC_ACCOUNT")
+		return RuntimeConfig{}, settings.UserPath, errors.New("owner is empty: set in config or via FAYNOSYNC_ACCOUNT")
 	}
 
 	return RuntimeConfig{
-		Token:  token,
-		Server: server,
-		Owner:  owner,
-	}, path, nil
+		Profile:          settings.Profile,
+		Server:           server,
+		Owner:            owner,
+		CredentialHelper: strings.TrimSpace(settings.Values.CredentialHelper),
+		OIDC:             settings.Values.OIDC,
+		HTTP:             settings.Values.HTTP,
+	}, settings.UserPath, nil
+}
+
+func tokenFromFile() (string, error) {
+	path := strings.TrimSpace(os.Getenv(EnvTokenFile))
+	if path == "" {
+		return "", nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", EnvTokenFile, err)
+	}
+
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", fmt.Errorf("%s points to an empty file: %s", EnvTokenFile, path)
+	}
+	return token, nil
 }
@@ -0,0 +1,734 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigratesLegacyConfigToDefaultProfile(t *testing.T) {
+	home := setHome(t)
+	path := filepath.Join(home, ".faynosync", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("server: https://updates.example.com\nowner: acme\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, _, err := Load("")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if cfg.Current != DefaultProfile {
+		t.Fatalf("unexpected current profile: %q", cfg.Current)
+	}
+	profile, err := cfg.Profile(DefaultProfile)
+	if err != nil {
+		t.Fatalf("default profile missing: %v", err)
+	}
+	if profile.Server != "https://updates.example.com" || profile.Owner != "acme" {
+		t.Fatalf("unexpected migrated profile: %+v", profile)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	if strings.Contains(string(raw), "profiles:") {
+		t.Fatalf("load must not rewrite the file:\n%s", raw)
+	}
+	if _, err := os.Stat(path + ".v0.bak"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("load must not write a backup, got %v", err)
+	}
+
+	if _, err := Update("", func(cfg *Config) error { return nil }); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	raw, _ = os.ReadFile(path)
+	if !strings.Contains(string(raw), "profiles:") {
+		t.Fatalf("expected update to save the migrated file:\n%s", raw)
+	}
+	if _, err := os.Stat(path + ".v0.bak"); err != nil {
+		t.Fatalf("expected backup before the update saved: %v", err)
+	}
+}
+
+func TestLoadReadOnlyLegacyConfig(t *testing.T) {
+	home := setHome(t)
+	dir := filepath.Join(home, ".faynosync")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("server: https://updates.example.com\nowner: acme\n"), 0o400); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.Chmod(dir, 0o500); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(dir, 0o755) })
+
+	if _, _, err := LoadServer("", ""); err != nil {
+		t.Fatalf("expected a read-only legacy config to load, got %v", err)
+	}
+}
+
+func TestLoadServerSelectsProfile(t *testing.T) {
+	setHome(t)
+	t.Setenv(EnvURL, "")
+	t.Setenv(EnvAccount, "")
+	t.Setenv(EnvProfile, "")
+
+	cfg := Config{Current: "prod"}
+	cfg.SetProfile("prod", Profile{Server: "https://prod", Owner: "acme"})
+	cfg.SetProfile("staging", Profile{Server: "https://staging", Owner: "acme-dev"})
+	if _, err := Init("", cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	got, _, err := LoadServer("", "")
+	if err != nil || got.Server != "https://prod" {
+		t.Fatalf("expected current profile, got %+v, %v", got, err)
+	}
+
+	t.Setenv(EnvProfile, "staging")
+	got, _, err = LoadServer("", "")
+	if err != nil || got.Server != "https://staging" || got.Profile != "staging" {
+		t.Fatalf("expected env profile, got %+v, %v", got, err)
+	}
+
+	got, _, err = LoadServer("", "prod")
+	if err != nil || got.Server != "https://prod" {
+		t.Fatalf("expected flag profile to win over env, got %+v, %v", got, err)
+	}
+
+	if _, _, err := LoadServer("", "missing"); err == nil {
+		t.Fatal("expected error for unknown profile")
+	}
+}
+
+func TestLoadRuntimeUsesCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script uses sh")
+	}
+
+	home := setHome(t)
+	t.Setenv(EnvToken, "")
+	t.Setenv(EnvURL, "")
+	t.Setenv(EnvAccount, "")
+	t.Setenv(EnvProfile, "")
+	resetHelperCache()
+	t.Cleanup(resetHelperCache)
+
+	counter := filepath.Join(home, "calls")
+	requestLog := filepath.Join(home, "request.json")
+	helper := filepath.Join(home, "helper.sh")
+	script := "#!/bin/sh\ncat > " + requestLog + "\necho x >> " + counter + "\necho '{\"token\":\"from-helper\"}'\n"
+	if err := os.WriteFile(helper, []byte(script), 0o755); err != nil {
+		t.Fatalf("write helper: %v", err)
+	}
+
+	cfg := Config{Current: "prod"}
+	cfg.SetProfile("prod", Profile{Server: "https://prod", Owner: "acme", CredentialHelper: helper})
+	if _, err := Init("", cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		got, _, err := LoadRuntime("", "")
+		if err != nil || got.Token != "from-helper" {
+			t.Fatalf("expected helper token, got %+v, %v", got, err)
+		}
+	}
+
+	calls, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	if n := strings.Count(string(calls), "x"); n != 1 {
+		t.Fatalf("expected helper to run once per process, ran %d times", n)
+	}
+
+	request, err := os.ReadFile(requestLog)
+	if err != nil {
+		t.Fatalf("read request: %v", err)
+	}
+	if string(request) != `{"server":"https://prod","owner":"acme","profile":"prod"}` {
+		t.Fatalf("unexpected helper request: %s", request)
+	}
+
+	t.Setenv(EnvToken, "env-token")
+	got, _, err := LoadRuntime("", "")
+	if err != nil || got.Token != "env-token" {
+		t.Fatalf("expected env token to take precedence, got %+v, %v", got, err)
+	}
+}
+
+func TestResolveMergesProjectConfigFoundInParent(t *testing.T) {
+	setHome(t)
+	t.Setenv(EnvURL, "")
+	t.Setenv(EnvAccount, "env-owner")
+	t.Setenv(EnvProfile, "")
+
+	cfg := Config{Current: "prod"}
+	cfg.SetProfile("prod", Profile{Server: "https://user", Owner: "user-owner", Upload: Upload{Channel: "stable"}})
+	if _, err := Init("", cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	repo := t.TempDir()
+	project := filepath.Join(repo, ProjectFileName)
+	if err := os.WriteFile(project, []byte("upload:\n  app: desktop\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+	nested := filepath.Join(repo, "cmd", "tool")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Chdir(nested)
+
+	settings, err := Resolve("", "")
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if settings.ProjectPath != project {
+		t.Fatalf("unexpected project path: %q", settings.ProjectPath)
+	}
+
+	for key, want := range map[string]struct{ value, origin string }{
+		"server":         {"https://user", "user "},
+		"owner":          {"env-owner", "env " + EnvAccount},
+		"upload.app":     {"desktop", "project " + project},
+		"upload.channel": {"stable", "user "},
+	} {
+		var found *Entry
+		for _, entry := range settings.Entries() {
+			if entry.Key == key {
+				found = &entry
+			}
+		}
+		if found == nil {
+			t.Fatalf("missing entry %s", key)
+		}
+		if found.Value != want.value || !strings.HasPrefix(found.Origin, want.origin) {
+			t.Fatalf("%s: got %q from %q, want %q from %q", key, found.Value, found.Origin, want.value, want.origin)
+		}
+	}
+	if got := settings.Origin("upload.arch"); got != SourceDefault {
+		t.Fatalf("unset key should come from defaults, got %q", got)
+	}
+}
+
+func TestLoadProjectRejectsCredentialHelper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ProjectFileName)
+	if err := os.WriteFile(path, []byte("credential_helper: sh -c evil\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+
+	if _, err := LoadProject(path); err == nil {
+		t.Fatal("expected credential_helper to be rejected in project config")
+	}
+}
+
+func TestLoadProjectRejectsServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ProjectFileName)
+	if err := os.WriteFile(path, []byte("server: https://evil\nupload:\n  app: desktop\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+
+	if _, err := LoadProject(path); err == nil || !strings.Contains(err.Error(), "server is only allowed in the user config") {
+		t.Fatalf("expected server to be rejected in project config, got %v", err)
+	}
+
+	problems, err := ValidateProjectFile(path)
+	if err != nil {
+		t.Fatalf("ValidateProjectFile returned error: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Line != 1 || problems[0].Key != "server" {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+}
+
+func TestStoredTokensAreKeptPerServer(t *testing.T) {
+	setHome(t)
+	if _, err := StoreToken("default", "https://a.example.com/", "token-a"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+	if _, err := StoreToken("default", "https://b.example.com", "token-b"); err != nil {
+		t.Fatalf("StoreToken returned error: %v", err)
+	}
+
+	for server, want := range map[string]string{"https://a.example.com": "token-a", "https://b.example.com/": "token-b"} {
+		if got, err := StoredToken("default", server); err != nil || got != want {
+			t.Fatalf("expected %q for %s, got %q, %v", want, server, got, err)
+		}
+	}
+	if _, err := StoredToken("default", "https://c.example.com"); err == nil {
+		t.Fatal("expected a token from another server to be refused")
+	}
+
+	if removed, err := RemoveToken("default", "https://a.example.com"); err != nil || !removed {
+		t.Fatalf("RemoveToken returned %v, %v", removed, err)
+	}
+	if got, _ := StoredToken("default", "https://b.example.com"); got != "token-b" {
+		t.Fatalf("expected the other server's token to stay, got %q", got)
+	}
+}
+
+func setHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(EnvConfig, "")
+	return home
+}
+
+func TestPathPrefersOverrideThenEnvThenXDG(t *testing.T) {
+	home := setHome(t)
+	legacy := filepath.Join(home, ".faynosync", "config.yaml")
+
+	got, err := Path("")
+	if err != nil || got != legacy {
+		t.Fatalf("expected legacy path, got %q, %v", got, err)
+	}
+
+	xdgHome := filepath.Join(home, "xdg")
+	t.Setenv("XDG_CONFIG_HOME", xdgHome)
+	got, _ = Path("")
+	if want := filepath.Join(xdgHome, "faynosync", "config.yaml"); got != want {
+		t.Fatalf("expected XDG path %q, got %q", want, got)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(legacy), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(legacy, []byte("current: default\n"), 0o644); err != nil {
+		t.Fatalf("write legacy config: %v", err)
+	}
+	got, _ = Path("")
+	if got != legacy {
+		t.Fatalf("expected existing legacy config to be kept, got %q", got)
+	}
+
+	t.Setenv(EnvConfig, filepath.Join(home, "env.yaml"))
+	got, _ = Path("")
+	if got != filepath.Join(home, "env.yaml") {
+		t.Fatalf("expected %s path, got %q", EnvConfig, got)
+	}
+
+	got, _ = Path(filepath.Join(home, "flag.yaml"))
+	if got != filepath.Join(home, "flag.yaml") {
+		t.Fatalf("expected --config path, got %q", got)
+	}
+}
+
+func TestDirIgnoresConfigOverrides(t *testing.T) {
+	home := setHome(t)
+	t.Setenv(EnvConfig, filepath.Join(home, "ci", "config.yaml"))
+
+	got, err := CredentialsPath()
+	if want := filepath.Join(home, ".faynosync", "credentials.yaml"); err != nil || got != want {
+		t.Fatalf("expected credentials in %q, got %q, %v", want, got, err)
+	}
+}
+
+func TestSetGetUnsetDottedKeys(t *testing.T) {
+	var profile Profile
+	for key, value := range map[string]string{
+		"upload.channel":      "nightly",
+		"upload.files":        "dist/*.zip, dist/*.dmg",
+		"http.timeout":        "90s",
+		"http.retries":        "3",
+		"http.headers.X-Team": "release",
+		"oidc.token_env":      "CI_JWT",
+		"http.proxy":          "socks5://proxy:1080",
+		"server":              "https://updates.example.com",
+		"http.insecure":       "true",
+		"http.min_tls":        "1.3",
+	} {
+		if err := SetKey(&profile, key, value); err != nil {
+			t.Fatalf("SetKey(%s): %v", key, err)
+		}
+	}
+
+	if !profile.HTTP.Insecure || profile.HTTP.MinTLS != "1.3" {
+		t.Fatalf("unexpected TLS settings: %+v", profile.HTTP)
+	}
+	if profile.HTTP.Retries != 3 || profile.HTTP.Headers["X-Team"] != "release" || len(profile.Upload.Files) != 2 {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	if got, _ := GetKey(profile, "upload.files"); got != "dist/*.zip,dist/*.dmg" {
+		t.Fatalf("unexpected upload.files: %q", got)
+	}
+	if got, _ := GetKey(profile, "http.headers.X-Team"); got != "release" {
+		t.Fatalf("unexpected header: %q", got)
+	}
+
+	if err := UnsetKey(&profile, "http.headers.X-Team"); err != nil {
+		t.Fatalf("UnsetKey: %v", err)
+	}
+	if err := UnsetKey(&profile, "http.retries"); err != nil {
+		t.Fatalf("UnsetKey: %v", err)
+	}
+	if _, ok := profile.HTTP.Headers["X-Team"]; ok || profile.HTTP.Retries != 0 {
+		t.Fatalf("expected keys to be removed: %+v", profile.HTTP)
+	}
+
+	for key, value := range map[string]string{
+		"http.timeout":  "soon",
+		"http.retries":  "-1",
+		"http.proxy":    "ftp://proxy",
+		"http.headers":  "X-Team=release",
+		"http.nope":     "1",
+		"http.min_tls":  "1.0",
+		"http.insecure": "maybe",
+	} {
+		if err := SetKey(&profile, key, value); err == nil {
+			t.Fatalf("expected SetKey(%s, %s) to fail", key, value)
+		}
+	}
+}
+
+func TestValidateFileReportsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `current: prod
+profiles:
+  prod:
+    server: https://updates.example.com
+    owner: acme
+    http:
+      timeout: soon
+      retries: many
+      timout: 5s
+  staging:
+    sever: https://staging
+extra: true
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	problems, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile returned error: %v", err)
+	}
+
+	want := []string{
+		"line 7: profiles.prod.http.timeout: invalid duration",
+		"line 8: profiles.prod.http.retries: expected an integer",
+		"line 9: profiles.prod.http.timout: unknown key",
+		"line 11: profiles.staging.sever: unknown key",
+		"line 12: extra: unknown key",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for i, problem := range problems {
+		if !strings.HasPrefix(problem.String(), want[i]) {
+			t.Fatalf("problem %d: got %q, want prefix %q", i, problem, want[i])
+		}
+	}
+}
+
+func TestValidateProjectFileRejectsUserOnlyKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ProjectFileName)
+	if err := os.WriteFile(path, []byte("upload:\n  app: desktop\nhttp:\n  proxy: http://evil:8080\n"), 0o644); err != nil {
+		t.Fatalf("write project config: %v", err)
+	}
+
+	problems, err := ValidateProjectFile(path)
+	if err != nil {
+		t.Fatalf("ValidateProjectFile returned error: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Line != 4 || problems[0].Key != "http.proxy" {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+}
+
+func TestConcurrentUpdatesKeepEveryChange(t *testing.T) {
+	setHome(t)
+	if _, err := Init("", Default()); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+
+	const writers = 8
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		name := fmt.Sprintf("p%d", i)
+		go func() {
+			_, err := Update("", func(cfg *Config) error {
+				cfg.SetProfile(name, Profile{Server: "https://" + name, Owner: name})
+				return nil
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < writers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Update returned error: %v", err)
+		}
+	}
+
+	cfg, path, err := Load("")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(cfg.Profiles) != writers+1 {
+		t.Fatalf("expected %d profiles, got %v", writers+1, cfg.ProfileNames())
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat config: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600 config file, got %v", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Fatalf("temp file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestSaveAtTightensExistingFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not meaningful on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("current: default\n"), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if !WorldReadable(path) {
+		t.Fatal("expected 0644 file to be world-readable")
+	}
+
+	if err := SaveAt(path, Default()); err != nil {
+		t.Fatalf("SaveAt returned error: %v", err)
+	}
+	if WorldReadable(path) {
+		t.Fatal("expected SaveAt to write a 0600 file")
+	}
+}
+
+func TestMigrateKeepsCommentsAndBacksUp(t *testing.T) {
+	home := setHome(t)
+	path := filepath.Join(home, ".faynosync", "config.yaml")
+	legacy := "# release server\nserver: https://updates.example.com\nowner: acme\n"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	applied, backup, err := MigrateFile(path)
+	if err != nil {
+		t.Fatalf("MigrateFile returned error: %v", err)
+	}
+	if len(applied) != 1 || applied[0].From != 0 || applied[0].To != CurrentVersion {
+		t.Fatalf("unexpected migrations: %+v", applied)
+	}
+
+	saved, err := os.ReadFile(backup)
+	if err != nil || string(saved) != legacy {
+		t.Fatalf("expected backup with original content, got %q, %v", saved, err)
+	}
+	raw, _ := os.ReadFile(path)
+	if !strings.Contains(string(raw), "# release server") || !strings.HasPrefix(string(raw), "version: 1") {
+		t.Fatalf("expected versioned file that keeps comments:\n%s", raw)
+	}
+
+	if applied, _, err := MigrateFile(path); err != nil || len(applied) != 0 {
+		t.Fatalf("expected second migration to be a no-op, got %+v, %v", applied, err)
+	}
+}
+
+func TestLoadRejectsNewerVersion(t *testing.T) {
+	home := setHome(t)
+	path := filepath.Join(home, ".faynosync", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("version: 99\ncurrent: default\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, _, err := Load(""); err == nil || !strings.Contains(err.Error(), "upgrade the CLI") {
+		t.Fatalf("expected newer version error, got %v", err)
+	}
+}
+
+func TestLoadExpandsVariablesAndIncludes(t *testing.T) {
+	home := setHome(t)
+	dir := filepath.Join(home, ".faynosync")
+	if err := os.MkdirAll(filepath.Join(dir, "team"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	team := "profiles:\n  prod:\n    http:\n      timeout: 30s\n      retries: ${TEAM_RETRIES:-2}\n"
+	if err := os.WriteFile(filepath.Join(dir, "team", "defaults.yaml"), []byte(team), 0o644); err != nil {
+		t.Fatalf("write include: %v", err)
+	}
+	main := "version: 1\ninclude: team/defaults.yaml\ncurrent: prod\nprofiles:\n  prod:\n    server: ${FAYNOSYNC_SERVER:-https://updates.internal}\n    owner: acme\n    http:\n      timeout: 90s\n      headers:\n        X-Literal: $${NOT_EXPANDED}\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(main), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	t.Setenv("FAYNOSYNC_SERVER", "")
+
+	cfg, _, err := Load("")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	prod := cfg.Profiles["prod"]
+	if prod.Server != "https://updates.internal" || prod.Owner != "acme" {
+		t.Fatalf("expected default and included values, got %+v", prod)
+	}
+	if prod.HTTP.Timeout != "90s" || prod.HTTP.Retries != 2 {
+		t.Fatalf("expected main file to override include and ints to expand, got %+v", prod.HTTP)
+	}
+	if prod.HTTP.Headers["X-Literal"] != "${NOT_EXPANDED}" {
+		t.Fatalf("expected $${ to stay literal, got %q", prod.HTTP.Headers["X-Literal"])
+	}
+
+	t.Setenv("FAYNOSYNC_SERVER", "https://from-env")
+	if _, err := Update("", func(cfg *Config) error {
+		cfg.Current = "prod"
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	raw, _ := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	for _, want := range []string{"${FAYNOSYNC_SERVER:-https://updates.internal}", "include:", "team/defaults.yaml"} {
+		if !strings.Contains(string(raw), want) {
+			t.Fatalf("expected save to keep %q:\n%s", want, raw)
+		}
+	}
+	if strings.Contains(string(raw), "https://from-env") || strings.Contains(string(raw), "retries") {
+		t.Fatalf("save must not write expanded or included values:\n%s", raw)
+	}
+}
+
+func TestUpdateKeepsReferencesOnTypedKeys(t *testing.T) {
+	home := setHome(t)
+	path := filepath.Join(home, ".faynosync", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	content := "version: 1\ncurrent: prod\nprofiles:\n  prod:\n    server: https://prod\n    # owner is set per team\n    owner: acme\n    http:\n      retries: ${RETRIES:-2}\n      insecure: ${INSECURE}\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := Update("", func(cfg *Config) error {
+		profile := cfg.Profiles["prod"]
+		profile.Owner = "other"
+		profile.Upload.Channel = "beta"
+		cfg.SetProfile("prod", profile)
+		cfg.SetProfile("staging", Profile{Server: "https://staging", Owner: "acme"})
+		return nil
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	for _, want := range []string{"retries: ${RETRIES:-2}", "insecure: ${INSECURE}", "# owner is set per team", "owner: other", "channel: beta", "staging:"} {
+		if !strings.Contains(string(raw), want) {
+			t.Fatalf("expected %q in saved config:\n%s", want, raw)
+		}
+	}
+
+	if _, err := Update("", func(cfg *Config) error {
+		return cfg.DeleteProfile("staging")
+	}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	raw, _ = os.ReadFile(path)
+	if strings.Contains(string(raw), "staging:") || !strings.Contains(string(raw), "retries: ${RETRIES:-2}") {
+		t.Fatalf("unexpected config after removing a profile:\n%s", raw)
+	}
+}
+
+func TestLoadReportsUndefinedVariableWithKeyPath(t *testing.T) {
+	home := setHome(t)
+	path := filepath.Join(home, ".faynosync", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("version: 1\nprofiles:\n  prod:\n    owner: ${FAYNOSYNC_TEST_UNDEFINED}\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	_, _, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "profiles.prod.owner: undefined variable FAYNOSYNC_TEST_UNDEFINED") {
+		t.Fatalf("expected undefined variable error with key path, got %v", err)
+	}
+
+	problems, err := ValidateFile(path)
+	if err != nil || len(problems) != 1 || problems[0].Line != 4 {
+		t.Fatalf("expected one problem on line 4, got %v, %v", problems, err)
+	}
+}
+
+func TestIncludeCannotSetUserOnlyKeys(t *testing.T) {
+	home := setHome(t)
+	dir := filepath.Join(home, ".faynosync")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("version: 1\ninclude: shared.yaml\ncurrent: prod\nprofiles:\n  prod:\n    owner: acme\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "shared.yaml"), []byte("profiles:\n  prod:\n    server: https://evil\n"), 0o600); err != nil {
+		t.Fatalf("write include: %v", err)
+	}
+
+	if _, _, err := Load(""); err == nil || !strings.Contains(err.Error(), "profiles.prod.server is only allowed in the user config") {
+		t.Fatalf("expected included server to be rejected, got %v", err)
+	}
+}
+
+func TestIncludeCycleIsRejected(t *testing.T) {
+	home := setHome(t)
+	dir := filepath.Join(home, ".faynosync")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("version: 1\ninclude: a.yaml\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("include: config.yaml\n"), 0o600); err != nil {
+		t.Fatalf("write include: %v", err)
+	}
+
+	if _, _, err := Load(""); err == nil || !strings.Contains(err.Error(), "includes itself") {
+		t.Fatalf("expected include cycle error, got %v", err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for value, want := range map[string]int64{
+		"2048": 2048,
+		"500K": 500 << 10,
+		"10M":  10 << 20,
+		"1g":   1 << 30,
+	} {
+		got, err := ParseRate(value)
+		if err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %d, %v, want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0", "-1M", "10MB", "fast"} {
+		if _, err := ParseRate(value); err == nil {
+			t.Errorf("ParseRate(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials maps a server to the credentials of each profile on it, so
+// config files that share a profile name keep separate tokens, and a token is
+// only ever sent back to the server that issued it.
+type Credentials struct {
+	Servers map[string]map[string]Credential `yaml:"servers"`
+}
+
+type Credential struct {
+	Token string `yaml:"token"`
+	// Username is kept from login, so an expired token can be renewed by
+	// asking for the password again. The password itself is never stored.
+	Username string `yaml:"username,omitempty"`
+}
+
+func CredentialsPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "credentials.yaml"), nil
+}
+
+func LoadCredentials() (Credentials, string, error) {
+	path, err := CredentialsPath()
+	if err != nil {
+		return Credentials{}, "", err
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Credentials{}, path, nil
+		}
+		return Credentials{}, "", err
+	}
+
+	var creds Credentials
+	if err := yaml.Unmarshal(raw, &creds); err != nil {
+		return Credentials{}, "", err
+	}
+
+	return creds, path, nil
+}
+
+func SaveCredentials(path string, creds Credentials) error {
+	out, err := yaml.Marshal(creds)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, out, 0o600)
+}
+
+func lockCredentials() (func(), error) {
+	path, err := CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	return Lock(path)
+}
+
+// StoreToken replaces the token of profile on server and keeps its stored
+// username.
+func StoreToken(profile, server, token string) (string, error) {
+	return updateCredential(profile, server, func(cred *Credential) {
+		cred.Token = strings.TrimSpace(token)
+	})
+}
+
+// StoreLogin saves the token from a login together with the username.
+func StoreLogin(profile, server, username, token string) (string, error) {
+	return updateCredential(profile, server, func(cred *Credential) {
+		cred.Token = strings.TrimSpace(token)
+		cred.Username = strings.TrimSpace(username)
+	})
+}
+
+func updateCredential(profile, server string, fn func(cred *Credential)) (string, error) {
+	unlock, err := lockCredentials()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	creds, path, err := LoadCredentials()
+	if err != nil {
+		return "", err
+	}
+
+	server = normalizeServer(server)
+	if creds.Servers == nil {
+		creds.Servers = map[string]map[string]Credential{}
+	}
+	if creds.Servers[server] == nil {
+		creds.Servers[server] = map[string]Credential{}
+	}
+	cred := creds.Servers[server][profile]
+	fn(&cred)
+	creds.Servers[server][profile] = cred
+
+	return path, SaveCredentials(path, creds)
+}
+
+func RemoveToken(profile, server string) (bool, error) {
+	unlock, err := lockCredentials()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	creds, path, err := LoadCredentials()
+	if err != nil {
+		return false, err
+	}
+
+	server = normalizeServer(server)
+	if _, ok := creds.Servers[server][profile]; !ok {
+		return false, nil
+	}
+	delete(creds.Servers[server], profile)
+	if len(creds.Servers[server]) == 0 {
+		delete(creds.Servers, server)
+	}
+
+	return true, SaveCredentials(path, creds)
+}
+
+func StoredToken(profile, server string) (string, error) {
+	cred, err := StoredCredential(profile, server)
+	return strings.TrimSpace(cred.Token), err
+}
+
+// StoredCredential returns the credential of profile on server. When only
+// another server has a token for the profile, the error names it instead of
+// sending that token here.
+func StoredCredential(profile, server string) (Credential, error) {
+	creds, _, err := LoadCredentials()
+	if err != nil {
+		return Credential{}, err
+	}
+
+	server = normalizeServer(server)
+	if cred, ok := creds.Servers[server][profile]; ok {
+		return cred, nil
+	}
+	for issuer, profiles := range creds.Servers {
+		if _, ok := profiles[profile]; ok {
+			return Credential{}, fmt.Errorf("the token stored for profile %q was issued by %s, not %s, run: faynosync login", profile, issuer, server)
+		}
+	}
+	return Credential{}, nil
+}
+
+func normalizeServer(server string) string {
+	return strings.TrimRight(strings.TrimSpace(server), "/")
+}
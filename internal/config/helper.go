@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+type helperRequest struct {
+	Server  string `json:"server"`
+	Owner   string `json:"owner"`
+	Profile string `json:"profile"`
+}
+
+type helperResponse struct {
+	Token string `json:"token"`
+}
+
+// helperKey identifies a cached token. The command is part of the key so a
+// changed or different helper for the same profile is always asked.
+type helperKey struct {
+	Command string
+	helperRequest
+}
+
+var (
+	helperMu    sync.Mutex
+	helperCache = map[helperKey]string{}
+)
+
+func newHelperKey(runtimeCfg RuntimeConfig) helperKey {
+	return helperKey{
+		Command: runtimeCfg.CredentialHelper,
+		helperRequest: helperRequest{
+			Server:  runtimeCfg.Server,
+			Owner:   runtimeCfg.Owner,
+			Profile: runtimeCfg.Profile,
+		},
+	}
+}
+
+// resetHelperCache forgets every cached token.
+func resetHelperCache() {
+	helperMu.Lock()
+	defer helperMu.Unlock()
+
+	helperCache = map[helperKey]string{}
+}
+
+// HelperToken runs the profile's credential helper, git-style: the request is
+// written to its stdin as JSON and the token is read from stdout, either as a
+// bare string or as {"token": "..."}. Results are cached for the process.
+func HelperToken(runtimeCfg RuntimeConfig) (string, error) {
+	key := newHelperKey(runtimeCfg)
+
+	helperMu.Lock()
+	defer helperMu.Unlock()
+
+	if token, ok := helperCache[key]; ok {
+		return token, nil
+	}
+
+	token, err := runHelper(key.Command, key.helperRequest)
+	if err != nil {
+		return "", err
+	}
+
+	helperCache[key] = token
+	return token, nil
+}
+
+// RefreshHelperToken discards the cached token and asks the helper again.
+func RefreshHelperToken(runtimeCfg RuntimeConfig) (string, error) {
+	helperMu.Lock()
+	delete(helperCache, newHelperKey(runtimeCfg))
+	helperMu.Unlock()
+
+	return HelperToken(runtimeCfg)
+}
+
+func runHelper(helper string, req helperRequest) (string, error) {
+	argv := strings.Fields(helper)
+	if len(argv) == 0 {
+		return "", fmt.Errorf("credential helper is empty")
+	}
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper %q failed: %w", argv[0], err)
+	}
+
+	out := strings.TrimSpace(stdout.String())
+	if strings.HasPrefix(out, "{") {
+		var resp helperResponse
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return "", fmt.Errorf("credential helper %q returned invalid JSON: %w", argv[0], err)
+		}
+		out = strings.TrimSpace(resp.Token)
+	}
+	if out == "" {
+		return "", fmt.Errorf("credential helper %q returned no token", argv[0])
+	}
+
+	return out, nil
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const maxIncludeDepth = 8
+
+// Includes holds the include directive, which accepts a single path or a
+// list of paths.
+type Includes []string
+
+func (i *Includes) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*i = Includes{node.Value}
+		return nil
+	}
+	var paths []string
+	if err := node.Decode(&paths); err != nil {
+		return errors.New("include must be a path or a list of paths")
+	}
+	*i = paths
+	return nil
+}
+
+// expandString replaces ${VAR} and ${VAR:-default} with values from the
+// environment. $${ produces a literal ${. The default is used when VAR is
+// unset or empty.
+func expandString(s string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			out.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		expr := s[start+2 : start+end]
+		name, fallback, hasDefault := strings.Cut(expr, ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in %q", s)
+		}
+
+		value := os.Getenv(name)
+		if value == "" {
+			if !hasDefault {
+				if _, set := os.LookupEnv(name); !set {
+					return "", fmt.Errorf("undefined variable %s", name)
+				}
+			}
+			value = fallback
+		}
+
+		out.WriteString(s[:start])
+		out.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
+
+// expandNode interpolates every scalar value below node. Keys are left as
+// they are. Errors name the dotted key path.
+func expandNode(node *yaml.Node, path string) error {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := path
+			if node.Kind == yaml.SequenceNode {
+				childPath = fmt.Sprintf("%s[%d]", path, i)
+			}
+			if err := expandNode(child, childPath); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := expandNode(node.Content[i+1], joinKey(path, node.Content[i].Value)); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+		value, err := expandString(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayKey(path), err)
+		}
+		setExpanded(node, value)
+	}
+	return nil
+}
+
+// setExpanded stores an interpolated value. Unquoted values get their type
+// resolved again, so "retries: ${RETRIES:-2}" still decodes as an integer.
+func setExpanded(node *yaml.Node, value string) {
+	node.Value = value
+	if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 {
+		node.Tag = ""
+	}
+}
+
+// resolveIncludes replaces the include directive of root with the content
+// of the named files. Values in root win over included ones, and later
+// includes win over earlier ones. Relative paths are resolved against dir.
+func resolveIncludes(root *yaml.Node, dir string, seen []string) (*yaml.Node, error) {
+	pair := removeMappingKey(root, "include")
+	if pair == nil {
+		return root, nil
+	}
+	if len(seen) > maxIncludeDepth {
+		return nil, fmt.Errorf("include: nested deeper than %d files", maxIncludeDepth)
+	}
+
+	var paths Includes
+	if err := pair[1].Decode(&paths); err != nil {
+		return nil, fmt.Errorf("line %d: %w", pair[1].Line, err)
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, path := range paths {
+		path, err := expandString(path)
+		if err != nil {
+			return nil, fmt.Errorf("include: %w", err)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		for _, prev := range seen {
+			if prev == path {
+				return nil, fmt.Errorf("include: %s includes itself", path)
+			}
+		}
+
+		included, err := readInclude(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkIncluded(included, path); err != nil {
+			return nil, err
+		}
+		included, err = resolveIncludes(included, filepath.Dir(path), append(seen, path))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		merged = mergeNodes(merged, included)
+	}
+	return mergeNodes(merged, root), nil
+}
+
+func readInclude(path string) (*yaml.Node, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+	migrated, _, err := Migrate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(migrated, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: included config must be a mapping", path)
+	}
+	return doc.Content[0], nil
+}
+
+// checkIncluded rejects user-only keys in an included file, so a shared
+// defaults file cannot redirect requests or run programs.
+func checkIncluded(root *yaml.Node, path string) error {
+	profiles := mappingValue(root, "profiles")
+	if profiles == nil || profiles.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		if key := findUserOnly(profiles.Content[i+1], ""); key != nil {
+			return fmt.Errorf("%s: line %d: profiles.%s.%s is only allowed in the user config", path, key.Line, profiles.Content[i].Value, key.Value)
+		}
+	}
+	return nil
+}
+
+// findUserOnly returns the first key below node that is user-only, with its
+// Value set to the full dotted key.
+func findUserOnly(node *yaml.Node, prefix string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := joinKey(prefix, node.Content[i].Value)
+		if isUserOnly(key) {
+			return &yaml.Node{Kind: yaml.ScalarNode, Value: key, Line: node.Content[i].Line}
+		}
+		if found := findUserOnly(node.Content[i+1], key); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// mergeNodes deep-merges two mappings; for anything else over replaces base.
+func mergeNodes(base, over *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || over.Kind != yaml.MappingNode {
+		return over
+	}
+
+	out := &yaml.Node{Kind: yaml.MappingNode, Tag: base.Tag, Content: append([]*yaml.Node(nil), base.Content...)}
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		key, value := over.Content[i], over.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(out.Content); j += 2 {
+			if out.Content[j].Value == key.Value {
+				out.Content[j+1] = mergeNodes(out.Content[j+1], value)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out.Content = append(out.Content, key, value)
+		}
+	}
+	return out
+}
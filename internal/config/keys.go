@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type leaf struct {
+	Key   string
+	Value reflect.Value
+}
+
+// leaves flattens a settings struct into dotted keys named after the yaml
+// tags, e.g. Upload.App becomes "upload.app".
+func leaves(v reflect.Value, prefix string) []leaf {
+	var out []leaf
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := yamlName(field)
+		if name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			out = append(out, leaves(value, key)...)
+			continue
+		}
+		out = append(out, leaf{Key: key, Value: value})
+	}
+	return out
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// Keys lists every dotted key a profile understands.
+func Keys() []string {
+	var keys []string
+	for _, l := range leaves(reflect.ValueOf(&Profile{}).Elem(), "") {
+		keys = append(keys, l.Key)
+	}
+	return keys
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		parts := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			parts = append(parts, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// tryLock falls back to an exclusive lock file where flock is unavailable.
+// A lock file left by a crashed process has to be removed by hand.
+func tryLock(path string) (func(), bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return func() {
+		_ = f.Close()
+		_ = os.Remove(path)
+	}, true, nil
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config format written by this build. Files without a
+// version key are version 0.
+const CurrentVersion = 1
+
+type migration struct {
+	From        int
+	Description string
+	Apply       func(root *yaml.Node) error
+}
+
+// migrations upgrade a file one version at a time. They edit the yaml node
+// tree, so comments and key order in the user's file survive.
+var migrations = []migration{
+	{From: 0, Description: "move top-level server and owner into the default profile", Apply: migrateToProfiles},
+}
+
+type Migration struct {
+	From        int
+	To          int
+	Description string
+}
+
+// Migrate upgrades raw to CurrentVersion. It returns the new file content and
+// the steps that were applied; with no steps, out is raw unchanged.
+func Migrate(raw []byte) ([]byte, []Migration, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return raw, nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config must be a mapping, got %s", root.Tag)
+	}
+
+	version := 0
+	if node := mappingValue(root, "version"); node != nil {
+		v, err := strconv.Atoi(node.Value)
+		if err != nil || v < 0 {
+			return nil, nil, fmt.Errorf("line %d: version: expected a non-negative integer, got %q", node.Line, node.Value)
+		}
+		version = v
+	}
+	if version > CurrentVersion {
+		return nil, nil, fmt.Errorf("config version %d is newer than this faynosync supports (%d), upgrade the CLI", version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return raw, nil, nil
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.From < version {
+			continue
+		}
+		if err := m.Apply(root); err != nil {
+			return nil, nil, fmt.Errorf("migrate config from version %d: %w", m.From, err)
+		}
+		applied = append(applied, Migration{From: m.From, To: m.From + 1, Description: m.Description})
+	}
+	setMappingValue(root, "version", strconv.Itoa(CurrentVersion), true)
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, applied, nil
+}
+
+// MigrateFile upgrades the config at path in place under lock. It returns the
+// applied steps and the backup path, which is empty when nothing changed.
+func MigrateFile(path string) ([]Migration, string, error) {
+	unlock, err := Lock(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, applied, backup, err := migrateFile(path, raw)
+	return applied, backup, err
+}
+
+// migrateFile writes a backup to <path>.v<version>.bak and the upgraded
+// content to path when raw is older than CurrentVersion.
+func migrateFile(path string, raw []byte) ([]byte, []Migration, string, error) {
+	out, applied, err := Migrate(raw)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if len(applied) == 0 {
+		return out, nil, "", nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, applied[0].From)
+	if _, err := os.Stat(backup); err != nil {
+		if err := writeFileAtomic(backup, raw, 0o600); err != nil {
+			return nil, nil, "", fmt.Errorf("back up config before migration: %w", err)
+		}
+	}
+	if err := writeFileAtomic(path, out, 0o600); err != nil {
+		return nil, nil, "", fmt.Errorf("migrate config: %w", err)
+	}
+	return out, applied, backup, nil
+}
+
+// Files written before profiles existed hold server and owner at the top
+// level.
+func migrateToProfiles(root *yaml.Node) error {
+	if profiles := mappingValue(root, "profiles"); profiles != nil && len(profiles.Content) > 0 {
+		return nil
+	}
+
+	profile := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range []string{"server", "owner"} {
+		profile.Content = append(profile.Content, removeMappingKey(root, key)...)
+	}
+	if len(profile.Content) == 0 {
+		return nil
+	}
+
+	removeMappingKey(root, "profiles")
+	root.Content = append(root.Content,
+		scalar("profiles"),
+		&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{scalar(DefaultProfile), profile}},
+	)
+	if current := mappingValue(root, "current"); current == nil || current.Value == "" {
+		setMappingValue(root, "current", DefaultProfile, true)
+	}
+	return nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeMappingKey deletes key and returns its key and value nodes, so they
+// can be moved together with their comments.
+func removeMappingKey(node *yaml.Node, key string) []*yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			pair := []*yaml.Node{node.Content[i], node.Content[i+1]}
+			node.Content = append(node.Content[:i:i], node.Content[i+2:]...)
+			return pair
+		}
+	}
+	return nil
+}
+
+func setMappingValue(node *yaml.Node, key, value string, first bool) {
+	if existing := mappingValue(node, key); existing != nil {
+		existing.Kind, existing.Tag, existing.Value = yaml.ScalarNode, "", value
+		return
+	}
+	pair := []*yaml.Node{scalar(key), scalar(value)}
+	if first {
+		node.Content = append(pair, node.Content...)
+		return
+	}
+	node.Content = append(node.Content, pair...)
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
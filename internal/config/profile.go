@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+func ActiveProfile(cfg Config, override string) string {
+	if name := strings.TrimSpace(override); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(os.Getenv(EnvProfile)); name != "" {
+		return name
+	}
+	if name := strings.TrimSpace(cfg.Current); name != "" {
+		return name
+	}
+	return DefaultProfile
+}
+
+func (c Config) Profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) SetProfile(name string, profile Profile) {
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[name] = profile
+}
+
+func (c *Config) Use(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+	c.Current = name
+	return nil
+}
+
+func (c *Config) DeleteProfile(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+	delete(c.Profiles, name)
+	if c.Current == name {
+		c.Current = ""
+	}
+	return nil
+}
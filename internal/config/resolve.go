@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const ProjectFileName = ".faynosync.yaml"
+
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+)
+
+// Settings is the effective profile after layering, lowest to highest:
+// defaults, user config, project config, environment. Command line flags are
+// applied on top by the caller.
+type Settings struct {
+	Profile     string
+	Values      Profile
+	Origins     map[string]string
+	UserPath    string
+	ProjectPath string
+
+	userErr error
+}
+
+func (s Settings) Origin(key string) string {
+	if origin, ok := s.Origins[key]; ok {
+		return origin
+	}
+	return SourceDefault
+}
+
+// Resolve layers the config file at path, or the default location when
+// path is empty, with the project file and the environment.
+func Resolve(path, profile string) (Settings, error) {
+	out := Settings{
+		Profile: ActiveProfile(Config{}, profile),
+		Origins: map[string]string{},
+	}
+
+	cfg, path, err := Load(path)
+	switch {
+	case err == nil:
+		out.UserPath = path
+		out.Profile = ActiveProfile(cfg, profile)
+		if user, profileErr := cfg.Profile(out.Profile); profileErr == nil {
+			out.merge(user, fmt.Sprintf("user %s (profile %s)", path, out.Profile))
+		} else {
+			out.userErr = profileErr
+		}
+	case isNotFound(err):
+		out.userErr = err
+	default:
+		return Settings{}, err
+	}
+
+	projectPath, err := FindProject()
+	if err != nil {
+		return Settings{}, err
+	}
+	if projectPath != "" {
+		project, err := LoadProject(projectPath)
+		if err != nil {
+			return Settings{}, err
+		}
+		out.ProjectPath = projectPath
+		out.merge(project, "project "+projectPath)
+	}
+
+	env := Profile{
+		Server: strings.TrimSpace(os.Getenv(EnvURL)),
+		Owner:  strings.TrimSpace(os.Getenv(EnvAccount)),
+	}
+	out.mergeEach(env, map[string]string{
+		"server": "env " + EnvURL,
+		"owner":  "env " + EnvAccount,
+	})
+
+	return out, nil
+}
+
+func (s *Settings) merge(layer Profile, source string) {
+	s.mergeEach(layer, map[string]string{"": source})
+}
+
+func (s *Settings) mergeEach(layer Profile, sources map[string]string) {
+	dst := leaves(reflect.ValueOf(&s.Values).Elem(), "")
+	src := leaves(reflect.ValueOf(&layer).Elem(), "")
+	for i, l := range src {
+		if l.Value.IsZero() {
+			continue
+		}
+		source, ok := sources[l.Key]
+		if !ok {
+			source = sources[""]
+		}
+		dst[i].Value.Set(l.Value)
+		s.Origins[l.Key] = source
+	}
+}
+
+// FindProject walks up from the working directory and returns the first
+// .faynosync.yaml it finds, or "" when there is none.
+func FindProject() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		candidate := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func LoadProject(path string) (Profile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var project Profile
+	if err := yaml.Unmarshal(raw, &project); err != nil {
+		return Profile{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, l := range leaves(reflect.ValueOf(&project).Elem(), "") {
+		if !isProjectKey(l.Key) && !l.Value.IsZero() {
+			return Profile{}, fmt.Errorf("%s: %s is only allowed in the user config", path, l.Key)
+		}
+	}
+	return project, nil
+}
+
+type Entry struct {
+	Key    string
+	Value  string
+	Origin string
+}
+
+func (s Settings) Entries() []Entry {
+	var out []Entry
+	for _, l := range leaves(reflect.ValueOf(&s.Values).Elem(), "") {
+		if l.Value.IsZero() {
+			continue
+		}
+		out = append(out, Entry{Key: l.Key, Value: formatValue(l.Value), Origin: s.Origin(l.Key)})
+	}
+	return out
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, errConfigNotFound)
+}
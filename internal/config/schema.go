@@ -0,0 +1,269 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Per-phase defaults. There is no overall deadline unless http.timeout is
+// set, so large uploads on slow links are only limited by the stall timeout.
+const (
+	DefaultConnectTimeout  = 30 * time.Second
+	DefaultTLSTimeout      = 10 * time.Second
+	DefaultResponseTimeout = 5 * time.Minute
+	DefaultStallTimeout    = time.Minute
+)
+
+// validators check a single value after it has been decoded into its field.
+var validators = map[string]func(string) error{
+	"server":                validateURL("http", "https"),
+	"http.timeout":          validateDuration,
+	"http.connect_timeout":  validateDuration,
+	"http.tls_timeout":      validateDuration,
+	"http.response_timeout": validateDuration,
+	"http.stall_timeout":    validateDuration,
+	"http.proxy":            validateURL("http", "https", "socks5", "socks5h"),
+	"http.min_tls":          validateTLSVersion,
+	"http.limit_rate": func(value string) error {
+		_, err := ParseRate(value)
+		return err
+	},
+}
+
+// TLSVersions maps the accepted http.min_tls values to crypto/tls constants.
+// Older versions are not offered; 1.2 is already the client default.
+var TLSVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// projectKeys are the only keys a project file may set. Everything else,
+// including where requests and tokens are sent, comes from the user config.
+var projectKeys = []string{"upload"}
+
+func isProjectKey(key string) bool {
+	return hasKeyPrefix(projectKeys, key)
+}
+
+// userOnlyKeys must be written in the user config itself, because they run
+// programs, read secrets or decide where requests and tokens are sent.
+var userOnlyKeys = []string{
+	"server", "owner", "credential_helper", "oidc",
+	"http.proxy", "http.no_proxy", "http.ca_bundle", "http.client_cert", "http.client_key", "http.insecure", "http.headers",
+}
+
+func isUserOnly(key string) bool {
+	return hasKeyPrefix(userOnlyKeys, key)
+}
+
+// hasKeyPrefix reports whether key is one of prefixes or nested under one.
+func hasKeyPrefix(prefixes []string, key string) bool {
+	for _, prefix := range prefixes {
+		if key == prefix || strings.HasPrefix(key, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetKey assigns value to a dotted key such as "http.timeout" or
+// "http.headers.X-Team". Lists take comma-separated values.
+func SetKey(profile *Profile, key, value string) error {
+	l, mapKey, err := lookup(profile, key)
+	if err != nil {
+		return err
+	}
+
+	switch l.Value.Kind() {
+	case reflect.String:
+		l.Value.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", key, value)
+		}
+		l.Value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s: expected a non-negative integer, got %q", key, value)
+		}
+		l.Value.SetInt(int64(n))
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		l.Value.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if l.Value.IsNil() {
+			l.Value.Set(reflect.MakeMap(l.Value.Type()))
+		}
+		l.Value.SetMapIndex(reflect.ValueOf(mapKey), reflect.ValueOf(value))
+		return nil
+	}
+
+	return validateKey(key, l.Value)
+}
+
+func UnsetKey(profile *Profile, key string) error {
+	l, mapKey, err := lookup(profile, key)
+	if err != nil {
+		return err
+	}
+
+	if l.Value.Kind() == reflect.Map {
+		if !l.Value.IsNil() {
+			l.Value.SetMapIndex(reflect.ValueOf(mapKey), reflect.Value{})
+		}
+		return nil
+	}
+	l.Value.Set(reflect.Zero(l.Value.Type()))
+	return nil
+}
+
+func GetKey(profile Profile, key string) (string, error) {
+	l, mapKey, err := lookup(&profile, key)
+	if err != nil {
+		return "", err
+	}
+
+	if l.Value.Kind() == reflect.Map {
+		value := l.Value.MapIndex(reflect.ValueOf(mapKey))
+		if !value.IsValid() {
+			return "", nil
+		}
+		return value.String(), nil
+	}
+	return formatValue(l.Value), nil
+}
+
+// lookup finds the field behind key. Map fields are addressed one entry at a
+// time, so for "http.headers.X-Team" it returns the headers map and "X-Team".
+func lookup(profile *Profile, key string) (leaf, string, error) {
+	key = strings.TrimSpace(key)
+	for _, l := range leaves(reflect.ValueOf(profile).Elem(), "") {
+		if l.Value.Kind() == reflect.Map {
+			if name, ok := strings.CutPrefix(key, l.Key+"."); ok && name != "" {
+				return l, name, nil
+			}
+			if key == l.Key {
+				return leaf{}, "", fmt.Errorf("%s is a map, use %s.<name>", key, key)
+			}
+			continue
+		}
+		if l.Key == key {
+			return l, "", nil
+		}
+	}
+	return leaf{}, "", fmt.Errorf("unknown key: %s (allowed: %s)", key, strings.Join(displayKeys(), ", "))
+}
+
+func displayKeys() []string {
+	var out []string
+	for _, l := range leaves(reflect.ValueOf(&Profile{}).Elem(), "") {
+		if l.Value.Kind() == reflect.Map {
+			out = append(out, l.Key+".<name>")
+			continue
+		}
+		out = append(out, l.Key)
+	}
+	return out
+}
+
+// Validate checks every value that is set in the profile.
+func (p Profile) Validate() error {
+	var errs []error
+	for _, l := range leaves(reflect.ValueOf(&p).Elem(), "") {
+		if err := validateKey(l.Key, l.Value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.HTTP.ClientKey != "" && p.HTTP.ClientCert == "" {
+		errs = append(errs, errors.New("http.client_key: set http.client_cert as well"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateKey(key string, value reflect.Value) error {
+	check, ok := validators[key]
+	if !ok || value.IsZero() {
+		return nil
+	}
+	if err := check(formatValue(value)); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func validateDuration(value string) error {
+	_, err := ParseTimeout(value)
+	return err
+}
+
+// ParseTimeout parses a positive duration such as 30s or 5m.
+func ParseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, use a value like 30s or 5m", value)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive, got %q", value)
+	}
+	return d, nil
+}
+
+func validateTLSVersion(value string) error {
+	if _, ok := TLSVersions[strings.TrimSpace(value)]; !ok {
+		return fmt.Errorf("unsupported TLS version %q, use 1.2 or 1.3", value)
+	}
+	return nil
+}
+
+// ParseRate parses a transfer rate in bytes per second. Like curl, it takes
+// an optional K, M or G suffix in multiples of 1024.
+func ParseRate(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	number := strings.TrimRight(value, "kKmMgG")
+	multiplier := int64(1)
+	switch strings.ToUpper(strings.TrimPrefix(value, number)) {
+	case "":
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	default:
+		return 0, fmt.Errorf("invalid rate %q, use a value like 500K or 10M", value)
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid rate %q, use a value like 500K or 10M", value)
+	}
+	return n * multiplier, nil
+}
+
+func validateURL(schemes ...string) func(string) error {
+	return func(value string) error {
+		u, err := url.Parse(strings.TrimSpace(value))
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid URL %q", value)
+		}
+		for _, scheme := range schemes {
+			if strings.EqualFold(u.Scheme, scheme) {
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported scheme %q, use %s", u.Scheme, strings.Join(schemes, ", "))
+	}
+}
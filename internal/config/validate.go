@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Problem struct {
+	Line    int
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Key, p.Message)
+}
+
+var profileType = reflect.TypeOf(Profile{})
+
+// ValidateFile reports unknown keys and invalid values in a user config file.
+func ValidateFile(path string) ([]Problem, error) {
+	root, err := readNode(path)
+	if err != nil || root == nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	eachKey(root, "", &problems, func(key, name string, value *yaml.Node) {
+		switch name {
+		case "version":
+			checkScalar(value, key, reflect.TypeOf(0), &problems)
+		case "include":
+			if err := value.Decode(new(Includes)); err != nil {
+				problems = append(problems, Problem{Line: value.Line, Key: key, Message: err.Error()})
+			}
+		case "current":
+			checkScalar(value, key, reflect.TypeOf(""), &problems)
+		case "server", "owner":
+			// Written by versions before profiles; migrated on load.
+			checkScalar(value, key, reflect.TypeOf(""), &problems)
+		case "profiles":
+			eachKey(value, key, &problems, func(profileKey, _ string, profile *yaml.Node) {
+				checkStruct(profile, profileKey, "", profileType, false, &problems)
+			})
+		default:
+			problems = append(problems, Problem{Line: value.Line, Key: key, Message: "unknown key"})
+		}
+	})
+	return sortProblems(problems), nil
+}
+
+// ValidateProjectFile is ValidateFile for .faynosync.yaml, which holds a
+// single profile at the top level.
+func ValidateProjectFile(path string) ([]Problem, error) {
+	root, err := readNode(path)
+	if err != nil || root == nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	checkStruct(root, "", "", profileType, true, &problems)
+	return sortProblems(problems), nil
+}
+
+func readNode(path string) (*yaml.Node, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// eachKey calls fn for every entry of a mapping node. display is the full
+// dotted path used in messages.
+func eachKey(node *yaml.Node, prefix string, problems *[]Problem, fn func(display, name string, value *yaml.Node)) {
+	if node.Kind != yaml.MappingNode {
+		*problems = append(*problems, Problem{Line: node.Line, Key: displayKey(prefix), Message: "expected a mapping"})
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		fn(joinKey(prefix, name), name, node.Content[i+1])
+	}
+}
+
+// checkStruct walks node against the yaml fields of t. display prefixes
+// messages, key is the profile-relative key used for validators.
+func checkStruct(node *yaml.Node, display, key string, t reflect.Type, project bool, problems *[]Problem) {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.IsExported() {
+			fields[yamlName(field)] = field
+		}
+	}
+
+	eachKey(node, display, problems, func(fieldDisplay, name string, value *yaml.Node) {
+		fieldKey := joinKey(key, name)
+		field, ok := fields[name]
+		if !ok {
+			*problems = append(*problems, Problem{Line: value.Line, Key: fieldDisplay, Message: "unknown key"})
+			return
+		}
+		if project && !isProjectKey(fieldKey) && field.Type.Kind() != reflect.Struct {
+			*problems = append(*problems, Problem{Line: value.Line, Key: fieldDisplay, Message: "only allowed in the user config"})
+			return
+		}
+		if field.Type.Kind() == reflect.Struct {
+			checkStruct(value, fieldDisplay, fieldKey, field.Type, project, problems)
+			return
+		}
+
+		if !project {
+			expanded, err := expandedCopy(value)
+			if err != nil {
+				*problems = append(*problems, Problem{Line: value.Line, Key: fieldDisplay, Message: err.Error()})
+				return
+			}
+			value = expanded
+		}
+
+		decoded := reflect.New(field.Type)
+		if err := value.Decode(decoded.Interface()); err != nil {
+			*problems = append(*problems, Problem{Line: value.Line, Key: fieldDisplay, Message: "expected " + describeType(field.Type)})
+			return
+		}
+		if decoded.Elem().Kind() == reflect.Int && decoded.Elem().Int() < 0 {
+			*problems = append(*problems, Problem{Line: value.Line, Key: fieldDisplay, Message: "must not be negative"})
+			return
+		}
+		if err := validateKey(fieldKey, decoded.Elem()); err != nil {
+			*problems = append(*problems, Problem{Line: value.Line, Key: fieldDisplay, Message: errorDetail(err, fieldKey)})
+		}
+	})
+}
+
+// expandedCopy interpolates ${VAR} references without touching node, so
+// line numbers of the original stay available.
+func expandedCopy(node *yaml.Node) (*yaml.Node, error) {
+	copied := *node
+	copied.Content = nil
+	for _, child := range node.Content {
+		expanded, err := expandedCopy(child)
+		if err != nil {
+			return nil, err
+		}
+		copied.Content = append(copied.Content, expanded)
+	}
+	if copied.Kind == yaml.ScalarNode && strings.Contains(copied.Value, "${") {
+		value, err := expandString(copied.Value)
+		if err != nil {
+			return nil, err
+		}
+		setExpanded(&copied, value)
+	}
+	return &copied, nil
+}
+
+// lenientCopy is expandedCopy for commands that rewrite the file. A reference
+// that cannot be resolved decodes as an empty value instead of failing, since
+// the file keeps it as written.
+func lenientCopy(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = nil
+	for _, child := range node.Content {
+		copied.Content = append(copied.Content, lenientCopy(child))
+	}
+	if copied.Kind == yaml.ScalarNode && strings.Contains(copied.Value, "${") {
+		value, err := expandString(copied.Value)
+		if err != nil {
+			value = ""
+		}
+		setExpanded(&copied, value)
+	}
+	return &copied
+}
+
+func checkScalar(node *yaml.Node, key string, t reflect.Type, problems *[]Problem) {
+	if err := node.Decode(reflect.New(t).Interface()); err != nil || node.Kind != yaml.ScalarNode {
+		*problems = append(*problems, Problem{Line: node.Line, Key: key, Message: "expected " + describeType(t)})
+	}
+}
+
+func describeType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int:
+		return "an integer"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Slice:
+		return "a list of strings"
+	case reflect.Map:
+		return "a mapping of strings"
+	default:
+		return "a string"
+	}
+}
+
+// errorDetail drops the "key: " prefix validateKey adds, since problems
+// already carry the key.
+func errorDetail(err error, key string) string {
+	return strings.TrimPrefix(err.Error(), key+": ")
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func displayKey(key string) string {
+	if key == "" {
+		return "(root)"
+	}
+	return key
+}
+
+func sortProblems(problems []Problem) []Problem {
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const lockTimeout = 10 * time.Second
+
+// writeFileAtomic writes data to a temp file next to path and renames it into
+// place, so readers see either the old or the new content, never a mix.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Lock takes an advisory lock for a read-modify-write cycle on path and
+// returns the function that releases it.
+func Lock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		unlock, ok, err := tryLock(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return unlock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s, held by another faynosync process", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Update loads the config file under lock, applies fn and saves the result.
+// Only the keys fn changed are rewritten in the file as written, so includes,
+// comments and ${VAR} references elsewhere are kept.
+func Update(path string, fn func(cfg *Config) error) (string, error) {
+	return update(path, false, fn)
+}
+
+// UpdateOrCreate is Update for a config file that may not exist yet; fn then
+// starts from an empty config.
+func UpdateOrCreate(path string, fn func(cfg *Config) error) (string, error) {
+	return update(path, true, fn)
+}
+
+func update(path string, create bool, fn func(cfg *Config) error) (string, error) {
+	path, err := Path(path)
+	if err != nil {
+		return "", err
+	}
+
+	unlock, err := Lock(path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && create:
+		raw = nil
+	case errors.Is(err, os.ErrNotExist):
+		return "", errConfigNotFound
+	case err != nil:
+		return "", err
+	}
+
+	// Migrating here, under the lock, also writes the .bak backup before
+	// the file is saved in the new format.
+	migrated, _, _, err := migrateFile(path, raw)
+	if err != nil {
+		return "", err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(migrated, &doc); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+
+	var cfg Config
+	if err := lenientCopy(root).Decode(&cfg); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	var before yaml.Node
+	if err := before.Encode(cfg); err != nil {
+		return "", err
+	}
+	if err := fn(&cfg); err != nil {
+		return "", err
+	}
+	cfg.Version = CurrentVersion
+	var after yaml.Node
+	if err := after.Encode(cfg); err != nil {
+		return "", err
+	}
+
+	doc.Content[0] = patchNode(root, &before, &after)
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	return path, writeFileAtomic(path, out, 0o600)
+}
+
+// patchNode applies the difference between before and after to orig, the
+// node as written. Values that did not change keep their original node.
+func patchNode(orig, before, after *yaml.Node) *yaml.Node {
+	if sameNode(before, after) {
+		return orig
+	}
+	if orig.Kind != yaml.MappingNode || before.Kind != yaml.MappingNode || after.Kind != yaml.MappingNode {
+		return after
+	}
+
+	for i := 0; i+1 < len(before.Content); i += 2 {
+		if mappingValue(after, before.Content[i].Value) == nil {
+			removeMappingKey(orig, before.Content[i].Value)
+		}
+	}
+	for i := 0; i+1 < len(after.Content); i += 2 {
+		key, value := after.Content[i], after.Content[i+1]
+		j := mappingIndex(orig, key.Value)
+		switch was := mappingValue(before, key.Value); {
+		case j < 0:
+			orig.Content = append(orig.Content, key, value)
+		case was == nil:
+			orig.Content[j+1] = value
+		default:
+			orig.Content[j+1] = patchNode(orig.Content[j+1], was, value)
+		}
+	}
+	return orig
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameNode(a, b *yaml.Node) bool {
+	x, errX := yaml.Marshal(a)
+	y, errY := yaml.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// WorldReadable reports whether other users can read the file at path. It is
+// always false on Windows, where permission bits are not meaningful.
+func WorldReadable(path string) bool {
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().Perm()&0o004 != 0
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -8,9 +9,12 @@ import (
 )
 
 func main() {
+	ctx, stop := cli.SignalContext(context.Background())
 	app := cli.New(os.Stdin, os.Stdout)
-	if err := app.Run(os.Args[1:]); err != nil {
+	err := app.Run(ctx, os.Args[1:])
+	stop()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
+		os.Exit(cli.ExitCode(err))
 	}
 }